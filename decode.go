@@ -1,15 +1,17 @@
 package json
 
 import (
+	"context"
 	"encoding"
 	"io"
 	"reflect"
 	"strconv"
+	"sync"
 	"unsafe"
 )
 
 type decoder interface {
-	decode([]byte, int64, int64, unsafe.Pointer) (int64, error)
+	decode(*runtimeContext, int64, int64, unsafe.Pointer) (int64, error)
 	decodeStream(*stream, int64, unsafe.Pointer) error
 }
 
@@ -17,52 +19,145 @@ type Decoder struct {
 	s *stream
 }
 
+// runtimeContext holds the state shared by decoders while decoding a byte slice.
+type runtimeContext struct {
+	buf    []byte
+	option DecodeOption
+	ctx    context.Context
+	errs   DecodeErrors // errors collected by DecodeOptionCollectErrors
+}
+
+// DecodeOptionFlag is a flag of DecodeOption.
+type DecodeOptionFlag int
+
+const (
+	// DecodeOptionUseNumber is set by UseNumber.
+	DecodeOptionUseNumber DecodeOptionFlag = 1 << iota
+	// DecodeOptionDisallowUnknownFields is set by DisallowUnknownFields.
+	DecodeOptionDisallowUnknownFields
+	// DecodeOptionCollectErrors is set by CollectErrors.
+	DecodeOptionCollectErrors
+	// DecodeOptionUseMapSlice is set by UseMapSlice.
+	DecodeOptionUseMapSlice
+	// DecodeOptionUseInt64 is set by UseInt64.
+	DecodeOptionUseInt64
+	// DecodeOptionBorrow is set by UnmarshalBorrow to decode strings and RawMessages
+	// that refer to the input instead of copying it.
+	DecodeOptionBorrow
+)
+
+// DecodeOption holds the flags and the values of the options of a decoding.
+// It is set by the DecodeOptionFuncs given to the decoding.
+type DecodeOption struct {
+	Flag   DecodeOptionFlag
+	config decodeCompileConfig
+	limits *DecodeLimits
+}
+
+// apply applies optFuncs to opt.
+func (opt DecodeOption) apply(optFuncs []DecodeOptionFunc) DecodeOption {
+	for _, optFunc := range optFuncs {
+		optFunc(&opt)
+	}
+	return opt
+}
+
+// CaseSensitiveKeys is embedded in a struct to match the keys of a JSON object
+// to the fields of the struct case-sensitively when decoding.
+// The encoding of the struct is not affected.
+//
+//	type T struct {
+//		json.CaseSensitiveKeys
+//		ID string `json:"id"`
+//	}
+type CaseSensitiveKeys struct{}
+
 var (
-	unmarshalJSONType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
-	unmarshalTextType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	unmarshalJSONType        = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+	unmarshalJSONContextType = reflect.TypeOf((*UnmarshalerContext)(nil)).Elem()
+	unmarshalTextType        = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+	runtimeContextPool = sync.Pool{
+		New: func() interface{} {
+			return &runtimeContext{}
+		},
+	}
 )
 
+func takeRuntimeContext() *runtimeContext {
+	return runtimeContextPool.Get().(*runtimeContext)
+}
+
+func releaseRuntimeContext(ctx *runtimeContext) {
+	ctx.buf = nil
+	ctx.option = DecodeOption{}
+	ctx.ctx = nil
+	ctx.errs = nil
+	runtimeContextPool.Put(ctx)
+}
+
 const (
 	nul                   = '\000'
 	maxDecodeNestingDepth = 10000
 )
 
-func unmarshal(data []byte, v interface{}) error {
-	src := make([]byte, len(data)+1) // append nul byte to the end
-	copy(src, data)
+func unmarshal(data []byte, v interface{}, optFuncs ...DecodeOptionFunc) error {
+	header := (*emptyInterface)(unsafe.Pointer(&v))
+	return unmarshalSource(nil, withNul(data), data, header.typ, header.ptr, DecodeOption{}.apply(optFuncs))
+}
 
+func unmarshalContext(ctx context.Context, data []byte, v interface{}, optFuncs ...DecodeOptionFunc) error {
 	header := (*emptyInterface)(unsafe.Pointer(&v))
+	return unmarshalSource(ctx, withNul(data), data, header.typ, header.ptr, DecodeOption{}.apply(optFuncs))
+}
 
-	if err := validateType(header.typ, uintptr(header.ptr)); err != nil {
-		return err
-	}
-	dec, err := decodeCompileToGetDecoder(header.typ)
-	if err != nil {
-		return err
-	}
-	if _, err := dec.decode(src, 0, 0, header.ptr); err != nil {
-		return err
+func unmarshalNoEscape(data []byte, v interface{}, optFuncs ...DecodeOptionFunc) error {
+	header := (*emptyInterface)(unsafe.Pointer(&v))
+	return unmarshalSource(nil, withNul(data), data, header.typ, noescape(header.ptr), DecodeOption{}.apply(optFuncs))
+}
+
+func unmarshalBorrow(data []byte, v interface{}, optFuncs ...DecodeOptionFunc) error {
+	var src []byte
+	if cap(data) > len(data) && data[:len(data)+1][len(data)] == nul {
+		// decode data itself with the nul byte after the end
+		src = data[:len(data)+1]
+	} else {
+		src = withNul(data)
 	}
-	return nil
+	header := (*emptyInterface)(unsafe.Pointer(&v))
+	opt := DecodeOption{}.apply(optFuncs)
+	opt.Flag |= DecodeOptionBorrow
+	return unmarshalSource(nil, src, data, header.typ, header.ptr, opt)
 }
 
-func unmarshalNoEscape(data []byte, v interface{}) error {
-	src := make([]byte, len(data)+1) // append nul byte to the end
+// withNul returns a copy of data with the nul byte appended to the end.
+func withNul(data []byte) []byte {
+	src := make([]byte, len(data)+1)
 	copy(src, data)
+	return src
+}
 
-	header := (*emptyInterface)(unsafe.Pointer(&v))
-
-	if err := validateType(header.typ, uintptr(header.ptr)); err != nil {
+// unmarshalSource decodes src, which is data followed by the nul byte, into the value of typ at p.
+func unmarshalSource(ctx context.Context, src, data []byte, typ *rtype, p unsafe.Pointer, opt DecodeOption) error {
+	if err := validateType(typ, uintptr(p)); err != nil {
 		return err
 	}
-	dec, err := decodeCompileToGetDecoder(header.typ)
-	if err != nil {
+	if err := opt.limits.checkBytes(int64(len(data))); err != nil {
 		return err
 	}
-	if _, err := dec.decode(src, 0, 0, noescape(header.ptr)); err != nil {
+	dec, err := decodeCompileToGetDecoderWithConfig(typ, opt.config)
+	if err != nil {
 		return err
 	}
-	return nil
+	rctx := takeRuntimeContext()
+	rctx.buf = src
+	rctx.option = opt
+	rctx.ctx = ctx
+	_, err = dec.decode(rctx, 0, 0, p)
+	err = collectedErrors(opt.Flag, rctx.errs, err)
+	err = finishErrorPath(err)
+	releaseRuntimeContext(rctx)
+	return err
 }
 
 //nolint:staticcheck
@@ -110,6 +205,9 @@ func (d *Decoder) prepareForDecode() error {
 			if s.read() {
 				continue
 			}
+			if s.limitErr != nil {
+				return s.limitErr
+			}
 			return io.EOF
 		}
 		break
@@ -123,6 +221,37 @@ func (d *Decoder) prepareForDecode() error {
 // See the documentation for Unmarshal for details about
 // the conversion of JSON into a Go value.
 func (d *Decoder) Decode(v interface{}) error {
+	return d.decode(v)
+}
+
+// DecodeContext reads the next JSON-encoded value from its
+// input and stores it in the value pointed to by v with context.Context.
+// The context is passed to the UnmarshalJSON method of values implementing
+// UnmarshalerContext, and it is checked before reading more data from the
+// underlying reader so that decoding a slow stream can be cancelled.
+func (d *Decoder) DecodeContext(ctx context.Context, v interface{}) error {
+	d.s.ctx = ctx
+	err := d.decode(v)
+	d.s.ctx = nil
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// DecodeWithOption call Decode with DecodeOption.
+// The options are applied in addition to the ones enabled by UseNumber and
+// DisallowUnknownFields, and only for this call.
+func (d *Decoder) DecodeWithOption(v interface{}, optFuncs ...DecodeOptionFunc) error {
+	s := d.s
+	orgOption := s.option
+	s.option = s.option.apply(optFuncs)
+	err := d.decode(v)
+	s.option = orgOption
+	return err
+}
+
+func (d *Decoder) decode(v interface{}) error {
 	header := (*emptyInterface)(unsafe.Pointer(&v))
 	typ := header.typ
 	ptr := uintptr(header.ptr)
@@ -134,7 +263,7 @@ func (d *Decoder) Decode(v interface{}) error {
 		return err
 	}
 
-	dec, err := decodeCompileToGetDecoder(typ)
+	dec, err := decodeCompileToGetDecoderWithConfig(typ, d.s.option.config)
 	if err != nil {
 		return err
 	}
@@ -142,12 +271,45 @@ func (d *Decoder) Decode(v interface{}) error {
 		return err
 	}
 	s := d.s
+	s.errs = nil
+	s.positions = nil
+	s.located = 0
+	start := s.totalOffset()
 	if err := dec.decodeStream(s, 0, header.ptr); err != nil {
-		return err
+		if s.limitErr != nil {
+			return s.limitErr
+		}
+		if err := s.recoverTypeError(err, start, 0); err != nil {
+			return finishErrorPath(err)
+		}
 	}
 	s.reset()
 	s.bufSize = initBufSize
-	return nil
+	return finishErrorPath(collectedErrors(s.option.Flag, s.errs, nil))
+}
+
+// ErrorPosition returns the 1-based line and column in bytes of the offset of err in the input of the Decoder.
+// err must be returned by the last call of Decode or DecodeWithOption, or be an element of its DecodeErrors,
+// and be *SyntaxError, *UnmarshalTypeError or *MissingFieldError.
+// The position is computed from the part of the input that remains in the buffer only when ErrorPosition is called.
+// Otherwise, or if the line of err has already been discarded, ErrorPosition returns 0 for both.
+func (d *Decoder) ErrorPosition(err error) (line, column int) {
+	pos, ok := d.s.locateError(err)
+	if !ok {
+		return 0, 0
+	}
+	return pos.Line, pos.Column
+}
+
+// ErrorSource returns the line of the input of the Decoder that caused err followed by a line
+// that has a caret under the column of the error like the function ErrorSource.
+// err is the same as ErrorPosition. If the position of err is unknown, ErrorSource returns an empty string.
+func (d *Decoder) ErrorSource(err error) string {
+	pos, ok := d.s.locateError(err)
+	if !ok {
+		return ""
+	}
+	return pos.Format()
 }
 
 func (d *Decoder) More() bool {
@@ -227,8 +389,9 @@ END:
 // DisallowUnknownFields causes the Decoder to return an error when the destination
 // is a struct and the input contains object keys which do not match any
 // non-ignored, exported fields in the destination.
+// The keys are still accepted by a struct that has a field with the "inline" option.
 func (d *Decoder) DisallowUnknownFields() {
-	d.s.disallowUnknownFields = true
+	d.s.option.Flag |= DecodeOptionDisallowUnknownFields
 }
 
 func (d *Decoder) InputOffset() int64 {
@@ -238,5 +401,19 @@ func (d *Decoder) InputOffset() int64 {
 // UseNumber causes the Decoder to unmarshal a number into an interface{} as a
 // Number instead of as a float64.
 func (d *Decoder) UseNumber() {
-	d.s.useNumber = true
+	d.s.option.Flag |= DecodeOptionUseNumber
+}
+
+// UseInt64 causes the Decoder to unmarshal an integer into an interface{} as an
+// int64, or as a uint64 if it overflows int64. The other numbers are unmarshaled
+// as a float64, or as a Number with UseNumber.
+func (d *Decoder) UseInt64() {
+	d.s.option.Flag |= DecodeOptionUseInt64
+}
+
+// UseMapSlice causes the Decoder to unmarshal an object into an interface{} as a
+// MapSlice instead of as a map[string]interface{}, keeping the order of the keys.
+// It applies to the objects at all nesting levels, including the objects in a []interface{}.
+func (d *Decoder) UseMapSlice() {
+	d.s.option.Flag |= DecodeOptionUseMapSlice
 }
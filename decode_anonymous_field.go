@@ -26,10 +26,10 @@ func (d *anonymousFieldDecoder) decodeStream(s *stream, depth int64, p unsafe.Po
 	return d.dec.decodeStream(s, depth, unsafe.Pointer(uintptr(p)+d.offset))
 }
 
-func (d *anonymousFieldDecoder) decode(buf []byte, cursor, depth int64, p unsafe.Pointer) (int64, error) {
+func (d *anonymousFieldDecoder) decode(ctx *runtimeContext, cursor, depth int64, p unsafe.Pointer) (int64, error) {
 	if *(*unsafe.Pointer)(p) == nil {
 		*(*unsafe.Pointer)(p) = unsafe_New(d.structType)
 	}
 	p = *(*unsafe.Pointer)(p)
-	return d.dec.decode(buf, cursor, depth, unsafe.Pointer(uintptr(p)+d.offset))
+	return d.dec.decode(ctx, cursor, depth, unsafe.Pointer(uintptr(p)+d.offset))
 }
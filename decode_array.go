@@ -29,8 +29,8 @@ func newArrayDecoder(dec decoder, elemType *rtype, alen int, structName, fieldNa
 
 func (d *arrayDecoder) decodeStream(s *stream, depth int64, p unsafe.Pointer) error {
 	depth++
-	if depth > maxDecodeNestingDepth {
-		return errExceededMaxDepth(s.char(), s.cursor)
+	if err := s.option.limits.checkDepth(depth, s.char(), s.totalOffset()); err != nil {
+		return err
 	}
 
 	for {
@@ -46,9 +46,13 @@ func (d *arrayDecoder) decodeStream(s *stream, depth int64, p unsafe.Pointer) er
 			for {
 				s.cursor++
 				if idx < d.alen {
+					n, start := len(s.errs), s.totalOffset()
 					if err := d.valueDecoder.decodeStream(s, depth, unsafe.Pointer(uintptr(p)+uintptr(idx)*d.size)); err != nil {
-						return err
+						if err := s.recoverTypeError(err, start, depth); err != nil {
+							return prependElementPath(err, s.buf, s.offset, start, idx)
+						}
 					}
+					s.prependCollectedPath(n, PathElement{Index: idx})
 				} else {
 					if err := s.skipValue(depth); err != nil {
 						return err
@@ -65,6 +69,9 @@ func (d *arrayDecoder) decodeStream(s *stream, depth int64, p unsafe.Pointer) er
 					s.cursor++
 					return nil
 				case ',':
+					if err := s.option.limits.checkElements(int64(idx+1), s.totalOffset()); err != nil {
+						return err
+					}
 					continue
 				case nul:
 					if s.read() {
@@ -89,10 +96,11 @@ ERROR:
 	return errUnexpectedEndOfJSON("array", s.totalOffset())
 }
 
-func (d *arrayDecoder) decode(buf []byte, cursor, depth int64, p unsafe.Pointer) (int64, error) {
+func (d *arrayDecoder) decode(ctx *runtimeContext, cursor, depth int64, p unsafe.Pointer) (int64, error) {
+	buf := ctx.buf
 	depth++
-	if depth > maxDecodeNestingDepth {
-		return 0, errExceededMaxDepth(buf[cursor], cursor)
+	if err := ctx.option.limits.checkDepth(depth, buf[cursor], cursor); err != nil {
+		return 0, err
 	}
 
 	buflen := int64(len(buf))
@@ -121,10 +129,14 @@ func (d *arrayDecoder) decode(buf []byte, cursor, depth int64, p unsafe.Pointer)
 			for {
 				cursor++
 				if idx < d.alen {
-					c, err := d.valueDecoder.decode(buf, cursor, depth, unsafe.Pointer(uintptr(p)+uintptr(idx)*d.size))
+					n := len(ctx.errs)
+					c, err := d.valueDecoder.decode(ctx, cursor, depth, unsafe.Pointer(uintptr(p)+uintptr(idx)*d.size))
 					if err != nil {
-						return 0, err
+						if c, err = ctx.recoverTypeError(err, cursor, depth); err != nil {
+							return 0, prependElementPath(err, buf, 0, cursor, idx)
+						}
 					}
+					ctx.prependCollectedPath(n, PathElement{Index: idx})
 					cursor = c
 				} else {
 					c, err := skipValue(buf, cursor, depth)
@@ -144,6 +156,9 @@ func (d *arrayDecoder) decode(buf []byte, cursor, depth int64, p unsafe.Pointer)
 					cursor++
 					return cursor, nil
 				case ',':
+					if err := ctx.option.limits.checkElements(int64(idx+1), cursor); err != nil {
+						return 0, err
+					}
 					continue
 				default:
 					return 0, errInvalidCharacter(buf[cursor], "array", cursor)
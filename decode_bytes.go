@@ -15,7 +15,7 @@ type bytesDecoder struct {
 func byteUnmarshalerSliceDecoder(typ *rtype, structName string, fieldName string) decoder {
 	var unmarshalDecoder decoder
 	switch {
-	case rtype_ptrTo(typ).Implements(unmarshalJSONType):
+	case rtype_ptrTo(typ).Implements(unmarshalJSONType), rtype_ptrTo(typ).Implements(unmarshalJSONContextType):
 		unmarshalDecoder = newUnmarshalJSONDecoder(rtype_ptrTo(typ), structName, fieldName)
 	case rtype_ptrTo(typ).Implements(unmarshalTextType):
 		unmarshalDecoder = newUnmarshalTextDecoder(rtype_ptrTo(typ), structName, fieldName)
@@ -44,6 +44,9 @@ func (d *bytesDecoder) decodeStream(s *stream, depth int64, p unsafe.Pointer) er
 		s.reset()
 		return nil
 	}
+	if err := s.option.limits.checkString(int64(len(bytes)), s.totalOffset()); err != nil {
+		return err
+	}
 	decodedLen := base64.StdEncoding.DecodedLen(len(bytes))
 	buf := make([]byte, decodedLen)
 	if _, err := base64.StdEncoding.Decode(buf, bytes); err != nil {
@@ -54,14 +57,17 @@ func (d *bytesDecoder) decodeStream(s *stream, depth int64, p unsafe.Pointer) er
 	return nil
 }
 
-func (d *bytesDecoder) decode(buf []byte, cursor, depth int64, p unsafe.Pointer) (int64, error) {
-	bytes, c, err := d.decodeBinary(buf, cursor, depth, p)
+func (d *bytesDecoder) decode(ctx *runtimeContext, cursor, depth int64, p unsafe.Pointer) (int64, error) {
+	bytes, c, err := d.decodeBinary(ctx, cursor, depth, p)
 	if err != nil {
 		return 0, err
 	}
 	if bytes == nil {
 		return c, nil
 	}
+	if err := ctx.option.limits.checkString(int64(len(bytes)), cursor); err != nil {
+		return 0, err
+	}
 	cursor = c
 	decodedLen := base64.StdEncoding.DecodedLen(len(bytes))
 	b := make([]byte, decodedLen)
@@ -128,7 +134,8 @@ func (d *bytesDecoder) decodeStreamBinary(s *stream, depth int64, p unsafe.Point
 	return nil, errNotAtBeginningOfValue(s.totalOffset())
 }
 
-func (d *bytesDecoder) decodeBinary(buf []byte, cursor, depth int64, p unsafe.Pointer) ([]byte, int64, error) {
+func (d *bytesDecoder) decodeBinary(ctx *runtimeContext, cursor, depth int64, p unsafe.Pointer) ([]byte, int64, error) {
+	buf := ctx.buf
 	for {
 		switch buf[cursor] {
 		case ' ', '\n', '\t', '\r':
@@ -154,7 +161,7 @@ func (d *bytesDecoder) decodeBinary(buf []byte, cursor, depth int64, p unsafe.Po
 					Offset: cursor,
 				}
 			}
-			c, err := d.sliceDecoder.decode(buf, cursor, depth, p)
+			c, err := d.sliceDecoder.decode(ctx, cursor, depth, p)
 			if err != nil {
 				return nil, 0, err
 			}
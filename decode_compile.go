@@ -12,16 +12,63 @@ import (
 )
 
 var (
-	jsonNumberType = reflect.TypeOf(json.Number(""))
+	jsonNumberType        = reflect.TypeOf(json.Number(""))
+	caseSensitiveKeysType = type2rtype(reflect.TypeOf(CaseSensitiveKeys{}))
+	mapSliceType          = type2rtype(reflect.TypeOf(MapSlice(nil)))
+	cachedConfigDecoders  runtime.ConfigCache // configDecoderKey => decoder
 )
 
+// decodeCompileConfig is the configuration of the decoder compiler that can be changed for each decoding.
+// The zero value is the default configuration.
+type decodeCompileConfig struct {
+	naming        *NamingStrategy
+	caseSensitive bool   // match object keys to struct fields case-sensitively
+	tagKey        string // key of the struct tag to read. empty reads the json key
+}
+
+type decodeCompileContext struct {
+	structTypeToDecoder map[uintptr]decoder
+	config              decodeCompileConfig
+	defaults            []*fieldDefault // default values to parse after the decoders are compiled
+}
+
+func newDecodeCompileContext(config decodeCompileConfig) *decodeCompileContext {
+	return &decodeCompileContext{
+		structTypeToDecoder: map[uintptr]decoder{},
+		config:              config,
+	}
+}
+
+type configDecoderKey struct {
+	typeptr uintptr
+	config  decodeCompileConfig
+}
+
+// decodeCompileToGetDecoderWithConfig returns the decoder compiled with config.
+// The decoder is cached for each combination of type and configuration.
+func decodeCompileToGetDecoderWithConfig(typ *rtype, config decodeCompileConfig) (decoder, error) {
+	if config == (decodeCompileConfig{}) {
+		return decodeCompileToGetDecoder(typ)
+	}
+	key := configDecoderKey{typeptr: uintptr(unsafe.Pointer(typ)), config: config}
+	if dec, exists := cachedConfigDecoders.Load(key); exists {
+		return dec.(decoder), nil
+	}
+	dec, err := decodeCompileHead(typ, newDecodeCompileContext(config))
+	if err != nil {
+		return nil, err
+	}
+	cachedConfigDecoders.Store(key, dec)
+	return dec, nil
+}
+
 func decodeCompileToGetDecoderSlowPath(typeptr uintptr, typ *rtype) (decoder, error) {
 	decoderMap := loadDecoderMap()
 	if dec, exists := decoderMap[typeptr]; exists {
 		return dec, nil
 	}
 
-	dec, err := decodeCompileHead(typ, map[uintptr]decoder{})
+	dec, err := decodeCompileHead(typ, newDecodeCompileContext(decodeCompileConfig{}))
 	if err != nil {
 		return nil, err
 	}
@@ -29,39 +76,58 @@ func decodeCompileToGetDecoderSlowPath(typeptr uintptr, typ *rtype) (decoder, er
 	return dec, nil
 }
 
-func decodeCompileHead(typ *rtype, structTypeToDecoder map[uintptr]decoder) (decoder, error) {
+func decodeCompileHead(typ *rtype, ctx *decodeCompileContext) (decoder, error) {
+	if fn := lookupTypeDecoder(typ.Elem()); fn != nil {
+		return newTypeDecoder(typ, fn, "", ""), nil
+	}
 	switch {
-	case rtype_ptrTo(typ).Implements(unmarshalJSONType):
+	case rtype_ptrTo(typ).Implements(unmarshalJSONType), rtype_ptrTo(typ).Implements(unmarshalJSONContextType):
 		return newUnmarshalJSONDecoder(rtype_ptrTo(typ), "", ""), nil
 	case rtype_ptrTo(typ).Implements(unmarshalTextType):
 		return newUnmarshalTextDecoder(rtype_ptrTo(typ), "", ""), nil
 	}
-	return decodeCompile(typ.Elem(), "", "", structTypeToDecoder)
+	dec, err := decodeCompile(typ.Elem(), "", "", ctx)
+	if err != nil {
+		return nil, err
+	}
+	// the default values are parsed after all decoders are compiled,
+	// because the literal may be decoded by the decoder of a recursive type that is being compiled.
+	for _, defaultValue := range ctx.defaults {
+		if err := defaultValue.parse(); err != nil {
+			return nil, err
+		}
+	}
+	return dec, nil
 }
 
-func decodeCompile(typ *rtype, structName, fieldName string, structTypeToDecoder map[uintptr]decoder) (decoder, error) {
+func decodeCompile(typ *rtype, structName, fieldName string, ctx *decodeCompileContext) (decoder, error) {
+	if fn := lookupTypeDecoder(typ); fn != nil {
+		return newTypeDecoder(rtype_ptrTo(typ), fn, structName, fieldName), nil
+	}
 	switch {
-	case rtype_ptrTo(typ).Implements(unmarshalJSONType):
+	case rtype_ptrTo(typ).Implements(unmarshalJSONType), rtype_ptrTo(typ).Implements(unmarshalJSONContextType):
 		return newUnmarshalJSONDecoder(rtype_ptrTo(typ), structName, fieldName), nil
 	case rtype_ptrTo(typ).Implements(unmarshalTextType):
 		return newUnmarshalTextDecoder(rtype_ptrTo(typ), structName, fieldName), nil
+	case typ == mapSliceType:
+		return decodeCompileMapSlice(structName, fieldName)
 	}
 
 	switch typ.Kind() {
 	case reflect.Ptr:
-		return decodeCompilePtr(typ, structName, fieldName, structTypeToDecoder)
+		return decodeCompilePtr(typ, structName, fieldName, ctx)
 	case reflect.Struct:
-		return decodeCompileStruct(typ, structName, fieldName, structTypeToDecoder)
+		return decodeCompileStruct(typ, structName, fieldName, ctx)
 	case reflect.Slice:
 		elem := typ.Elem()
 		if elem.Kind() == reflect.Uint8 {
 			return decodeCompileBytes(elem, structName, fieldName)
 		}
-		return decodeCompileSlice(typ, structName, fieldName, structTypeToDecoder)
+		return decodeCompileSlice(typ, structName, fieldName, ctx)
 	case reflect.Array:
-		return decodeCompileArray(typ, structName, fieldName, structTypeToDecoder)
+		return decodeCompileArray(typ, structName, fieldName, ctx)
 	case reflect.Map:
-		return decodeCompileMap(typ, structName, fieldName, structTypeToDecoder)
+		return decodeCompileMap(typ, structName, fieldName, ctx)
 	case reflect.Interface:
 		return decodeCompileInterface(typ, structName, fieldName)
 	case reflect.Uintptr:
@@ -102,9 +168,22 @@ func decodeCompile(typ *rtype, structName, fieldName string, structTypeToDecoder
 	}
 }
 
+// hasCaseSensitiveKeys reports whether typ embeds CaseSensitiveKeys.
+func hasCaseSensitiveKeys(typ *rtype) bool {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.Anonymous && type2rtype(field.Type) == caseSensitiveKeysType {
+			return true
+		}
+	}
+	return false
+}
+
 func isStringTagSupportedType(typ *rtype) bool {
 	switch {
-	case rtype_ptrTo(typ).Implements(unmarshalJSONType):
+	case lookupTypeDecoder(typ) != nil:
+		return false
+	case rtype_ptrTo(typ).Implements(unmarshalJSONType), rtype_ptrTo(typ).Implements(unmarshalJSONContextType):
 		return false
 	case rtype_ptrTo(typ).Implements(unmarshalTextType):
 		return false
@@ -124,11 +203,11 @@ func isStringTagSupportedType(typ *rtype) bool {
 	return true
 }
 
-func decodeCompileMapKey(typ *rtype, structName, fieldName string, structTypeToDecoder map[uintptr]decoder) (decoder, error) {
+func decodeCompileMapKey(typ *rtype, structName, fieldName string, ctx *decodeCompileContext) (decoder, error) {
 	if rtype_ptrTo(typ).Implements(unmarshalTextType) {
 		return newUnmarshalTextDecoder(rtype_ptrTo(typ), structName, fieldName), nil
 	}
-	dec, err := decodeCompile(typ, structName, fieldName, structTypeToDecoder)
+	dec, err := decodeCompile(typ, structName, fieldName, ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -152,8 +231,8 @@ ERROR:
 	}
 }
 
-func decodeCompilePtr(typ *rtype, structName, fieldName string, structTypeToDecoder map[uintptr]decoder) (decoder, error) {
-	dec, err := decodeCompile(typ.Elem(), structName, fieldName, structTypeToDecoder)
+func decodeCompilePtr(typ *rtype, structName, fieldName string, ctx *decodeCompileContext) (decoder, error) {
+	dec, err := decodeCompile(typ.Elem(), structName, fieldName, ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -249,42 +328,56 @@ func decodeCompileBytes(typ *rtype, structName, fieldName string) (decoder, erro
 	return newBytesDecoder(typ, structName, fieldName), nil
 }
 
-func decodeCompileSlice(typ *rtype, structName, fieldName string, structTypeToDecoder map[uintptr]decoder) (decoder, error) {
+func decodeCompileSlice(typ *rtype, structName, fieldName string, ctx *decodeCompileContext) (decoder, error) {
 	elem := typ.Elem()
-	decoder, err := decodeCompile(elem, structName, fieldName, structTypeToDecoder)
+	decoder, err := decodeCompile(elem, structName, fieldName, ctx)
 	if err != nil {
 		return nil, err
 	}
 	return newSliceDecoder(decoder, elem, elem.Size(), structName, fieldName), nil
 }
 
-func decodeCompileArray(typ *rtype, structName, fieldName string, structTypeToDecoder map[uintptr]decoder) (decoder, error) {
+func decodeCompileArray(typ *rtype, structName, fieldName string, ctx *decodeCompileContext) (decoder, error) {
 	elem := typ.Elem()
-	decoder, err := decodeCompile(elem, structName, fieldName, structTypeToDecoder)
+	decoder, err := decodeCompile(elem, structName, fieldName, ctx)
 	if err != nil {
 		return nil, err
 	}
 	return newArrayDecoder(decoder, elem, typ.Len(), structName, fieldName), nil
 }
 
-func decodeCompileMap(typ *rtype, structName, fieldName string, structTypeToDecoder map[uintptr]decoder) (decoder, error) {
-	keyDec, err := decodeCompileMapKey(typ.Key(), structName, fieldName, structTypeToDecoder)
+func decodeCompileMap(typ *rtype, structName, fieldName string, ctx *decodeCompileContext) (decoder, error) {
+	keyDec, err := decodeCompileMapKey(typ.Key(), structName, fieldName, ctx)
 	if err != nil {
 		return nil, err
 	}
-	valueDec, err := decodeCompile(typ.Elem(), structName, fieldName, structTypeToDecoder)
+	valueDec, err := decodeCompile(typ.Elem(), structName, fieldName, ctx)
 	if err != nil {
 		return nil, err
 	}
 	return newMapDecoder(typ, typ.Key(), keyDec, typ.Elem(), valueDec, structName, fieldName), nil
 }
 
+func decodeCompileMapSlice(structName, fieldName string) (decoder, error) {
+	valueDecoder := newEmptyInterfaceDecoder(structName, fieldName)
+	// the nested objects keep the order of the keys as well
+	valueDecoder.useMapSlice = true
+	return newMapSliceDecoder(valueDecoder, structName, fieldName), nil
+}
+
 func decodeCompileInterface(typ *rtype, structName, fieldName string) (decoder, error) {
+	if u := runtime.LookupUnion(typ); u != nil {
+		return newUnionDecoder(typ, u, structName, fieldName), nil
+	}
 	return newInterfaceDecoder(typ, structName, fieldName), nil
 }
 
-func decodeRemoveConflictFields(fieldMap map[string]*structFieldSet, conflictedMap map[string]struct{}, dec *structDecoder, field reflect.StructField) {
+func decodeRemoveConflictFields(fieldMap map[string]*structFieldSet, conflictedMap map[string]struct{}, dec *structDecoder, field reflect.StructField, caseSensitive bool) {
 	for k, v := range dec.fieldMap {
+		if caseSensitive && k != v.key {
+			// lower case key for case-insensitive matching
+			continue
+		}
 		if _, exists := conflictedMap[k]; exists {
 			// already conflicted key
 			continue
@@ -297,21 +390,15 @@ func decodeRemoveConflictFields(fieldMap map[string]*structFieldSet, conflictedM
 				isTaggedKey: v.isTaggedKey,
 				key:         k,
 				keyLen:      int64(len(k)),
+				tracked:     v.tracked,
 			}
-			fieldMap[k] = fieldSet
-			lower := strings.ToLower(k)
-			if _, exists := fieldMap[lower]; !exists {
-				fieldMap[lower] = fieldSet
-			}
+			decodeAddFieldSet(fieldMap, k, fieldSet, caseSensitive)
 			continue
 		}
 		if set.isTaggedKey {
 			if v.isTaggedKey {
 				// conflict tag key
-				delete(fieldMap, k)
-				delete(fieldMap, strings.ToLower(k))
-				conflictedMap[k] = struct{}{}
-				conflictedMap[strings.ToLower(k)] = struct{}{}
+				decodeConflictFieldSet(fieldMap, conflictedMap, k, caseSensitive)
 			}
 		} else {
 			if v.isTaggedKey {
@@ -321,42 +408,64 @@ func decodeRemoveConflictFields(fieldMap map[string]*structFieldSet, conflictedM
 					isTaggedKey: v.isTaggedKey,
 					key:         k,
 					keyLen:      int64(len(k)),
+					tracked:     v.tracked,
 				}
-				fieldMap[k] = fieldSet
-				lower := strings.ToLower(k)
-				if _, exists := fieldMap[lower]; !exists {
-					fieldMap[lower] = fieldSet
-				}
+				decodeAddFieldSet(fieldMap, k, fieldSet, caseSensitive)
 			} else {
 				// conflict tag key
-				delete(fieldMap, k)
-				delete(fieldMap, strings.ToLower(k))
-				conflictedMap[k] = struct{}{}
-				conflictedMap[strings.ToLower(k)] = struct{}{}
+				decodeConflictFieldSet(fieldMap, conflictedMap, k, caseSensitive)
 			}
 		}
 	}
 }
 
-func decodeCompileStruct(typ *rtype, structName, fieldName string, structTypeToDecoder map[uintptr]decoder) (decoder, error) {
+// decodeAddFieldSet adds fieldSet for key.
+// Unless caseSensitive, the lower case key is added too if no other field has it.
+func decodeAddFieldSet(fieldMap map[string]*structFieldSet, key string, fieldSet *structFieldSet, caseSensitive bool) {
+	fieldMap[key] = fieldSet
+	if caseSensitive {
+		return
+	}
+	lower := strings.ToLower(key)
+	if _, exists := fieldMap[lower]; !exists {
+		fieldMap[lower] = fieldSet
+	}
+}
+
+func decodeConflictFieldSet(fieldMap map[string]*structFieldSet, conflictedMap map[string]struct{}, key string, caseSensitive bool) {
+	delete(fieldMap, key)
+	conflictedMap[key] = struct{}{}
+	if caseSensitive {
+		return
+	}
+	delete(fieldMap, strings.ToLower(key))
+	conflictedMap[strings.ToLower(key)] = struct{}{}
+}
+
+func decodeCompileStruct(typ *rtype, structName, fieldName string, ctx *decodeCompileContext) (decoder, error) {
 	fieldNum := typ.NumField()
 	conflictedMap := map[string]struct{}{}
 	fieldMap := map[string]*structFieldSet{}
 	typeptr := uintptr(unsafe.Pointer(typ))
-	if dec, exists := structTypeToDecoder[typeptr]; exists {
+	if dec, exists := ctx.structTypeToDecoder[typeptr]; exists {
 		return dec, nil
 	}
 	structDec := newStructDecoder(structName, fieldName, fieldMap)
-	structTypeToDecoder[typeptr] = structDec
+	ctx.structTypeToDecoder[typeptr] = structDec
 	structName = typ.Name()
+	structDec.typeName = structName
+	caseSensitive := ctx.config.caseSensitive || hasCaseSensitiveKeys(typ)
 	for i := 0; i < fieldNum; i++ {
 		field := typ.Field(i)
-		if runtime.IsIgnoredStructField(field) {
+		if runtime.IsIgnoredStructField(field, ctx.config.tagKey) {
+			continue
+		}
+		if field.Anonymous && type2rtype(field.Type) == caseSensitiveKeysType {
 			continue
 		}
 		isUnexportedField := unicode.IsLower([]rune(field.Name)[0])
-		tag := runtime.StructTagFromField(field)
-		dec, err := decodeCompile(type2rtype(field.Type), structName, field.Name, structTypeToDecoder)
+		tag := runtime.StructTagFromField(field, ctx.config.tagKey, ctx.config.naming)
+		dec, err := decodeCompile(type2rtype(field.Type), structName, field.Name, ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -366,7 +475,15 @@ func decodeCompileStruct(typ *rtype, structName, fieldName string, structTypeToD
 					// recursive definition
 					continue
 				}
-				decodeRemoveConflictFields(fieldMap, conflictedMap, stDec, field)
+				decodeRemoveConflictFields(fieldMap, conflictedMap, stDec, field, caseSensitive)
+				if stDec.inlineField != nil && structDec.inlineField == nil {
+					structDec.inlineField = &inlineFieldDecoder{
+						offset:     field.Offset + stDec.inlineField.offset,
+						dec:        stDec.inlineField.dec,
+						structType: stDec.inlineField.structType,
+						embedded:   stDec.inlineField.embedded,
+					}
+				}
 			} else if pdec, ok := dec.(*ptrDecoder); ok {
 				contentDec := pdec.contentDecoder()
 				if pdec.typ == typ {
@@ -381,7 +498,19 @@ func decodeCompileStruct(typ *rtype, structName, fieldName string, structTypeToD
 					)
 				}
 				if dec, ok := contentDec.(*structDecoder); ok {
+					if dec.inlineField != nil && structDec.inlineField == nil && !isUnexportedField {
+						structDec.inlineField = &inlineFieldDecoder{
+							offset:     field.Offset,
+							dec:        dec.inlineField.dec,
+							structType: pdec.typ,
+							embedded:   dec.inlineField,
+						}
+					}
 					for k, v := range dec.fieldMap {
+						if caseSensitive && k != v.key {
+							// lower case key for case-insensitive matching
+							continue
+						}
 						if _, exists := conflictedMap[k]; exists {
 							// already conflicted key
 							continue
@@ -395,21 +524,15 @@ func decodeCompileStruct(typ *rtype, structName, fieldName string, structTypeToD
 								key:         k,
 								keyLen:      int64(len(k)),
 								err:         fieldSetErr,
+								tracked:     v.tracked.withoutDefault(),
 							}
-							fieldMap[k] = fieldSet
-							lower := strings.ToLower(k)
-							if _, exists := fieldMap[lower]; !exists {
-								fieldMap[lower] = fieldSet
-							}
+							decodeAddFieldSet(fieldMap, k, fieldSet, caseSensitive)
 							continue
 						}
 						if set.isTaggedKey {
 							if v.isTaggedKey {
 								// conflict tag key
-								delete(fieldMap, k)
-								delete(fieldMap, strings.ToLower(k))
-								conflictedMap[k] = struct{}{}
-								conflictedMap[strings.ToLower(k)] = struct{}{}
+								decodeConflictFieldSet(fieldMap, conflictedMap, k, caseSensitive)
 							}
 						} else {
 							if v.isTaggedKey {
@@ -420,23 +543,19 @@ func decodeCompileStruct(typ *rtype, structName, fieldName string, structTypeToD
 									key:         k,
 									keyLen:      int64(len(k)),
 									err:         fieldSetErr,
+									tracked:     v.tracked.withoutDefault(),
 								}
-								fieldMap[k] = fieldSet
-								lower := strings.ToLower(k)
-								if _, exists := fieldMap[lower]; !exists {
-									fieldMap[lower] = fieldSet
-								}
+								decodeAddFieldSet(fieldMap, k, fieldSet, caseSensitive)
 							} else {
 								// conflict tag key
-								delete(fieldMap, k)
-								delete(fieldMap, strings.ToLower(k))
-								conflictedMap[k] = struct{}{}
-								conflictedMap[strings.ToLower(k)] = struct{}{}
+								decodeConflictFieldSet(fieldMap, conflictedMap, k, caseSensitive)
 							}
 						}
 					}
 				}
 			}
+		} else if mapDec, ok := dec.(*mapDecoder); ok && tag.IsInline {
+			structDec.inlineField = &inlineFieldDecoder{offset: field.Offset, dec: mapDec}
 		} else {
 			if tag.IsString && isStringTagSupportedType(type2rtype(field.Type)) {
 				dec = newWrappedStringDecoder(type2rtype(field.Type), dec, structName, field.Name)
@@ -454,14 +573,24 @@ func decodeCompileStruct(typ *rtype, structName, fieldName string, structTypeToD
 				key:         key,
 				keyLen:      int64(len(key)),
 			}
-			fieldMap[key] = fieldSet
-			lower := strings.ToLower(key)
-			if _, exists := fieldMap[lower]; !exists {
-				fieldMap[lower] = fieldSet
+			if tag.HasDefault {
+				defaultValue, err := newFieldDefault(type2rtype(field.Type), dec, typ.Name()+"."+field.Name, tag.Default)
+				if err != nil {
+					return nil, err
+				}
+				fieldSet.tracked.defaultValue = defaultValue
+				ctx.defaults = append(ctx.defaults, defaultValue)
+			}
+			if tag.IsRequired || tag.HasDefault {
+				fieldSet.tracked.key = key
+				fieldSet.tracked.isRequired = tag.IsRequired
 			}
+			decodeAddFieldSet(fieldMap, key, fieldSet, caseSensitive)
 		}
 	}
-	delete(structTypeToDecoder, typeptr)
+	delete(ctx.structTypeToDecoder, typeptr)
+	structDec.caseSensitive = caseSensitive
+	structDec.indexTrackedFields()
 	structDec.tryOptimize()
 	return structDec, nil
 }
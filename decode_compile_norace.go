@@ -15,7 +15,7 @@ func decodeCompileToGetDecoder(typ *rtype) (decoder, error) {
 		return dec, nil
 	}
 
-	dec, err := decodeCompileHead(typ, map[uintptr]decoder{})
+	dec, err := decodeCompileHead(typ, newDecodeCompileContext(decodeCompileConfig{}))
 	if err != nil {
 		return nil, err
 	}
@@ -23,7 +23,7 @@ func decodeCompileToGetDecoder(typ *rtype) (decoder, error) {
 	}
 	decMu.RUnlock()
 
-	dec, err := decodeCompileHead(typ, map[uintptr]decoder{})
+	dec, err := decodeCompileHead(typ, newDecodeCompileContext(decodeCompileConfig{}))
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,178 @@
+package json
+
+import (
+	"fmt"
+	"reflect"
+	"unsafe"
+)
+
+// fieldDefault is the default value of a struct field that is applied when the object doesn't have the key of the field.
+type fieldDefault struct {
+	typ     *rtype
+	dec     decoder
+	name    string        // the name of the field for errors
+	literal []byte        // the JSON literal terminated by nul
+	value   reflect.Value // the value decoded from the literal, or invalid until parse is called
+	parsing bool
+}
+
+// newFieldDefault returns the default value of typ given by literal.
+// The literal of a string type is the string itself, and the literal of other types is a JSON literal.
+// The literal is parsed by parse after dec and the decoders it refers to are compiled.
+func newFieldDefault(typ *rtype, dec decoder, name, literal string) (*fieldDefault, error) {
+	src := []byte(literal)
+	if isStringDefaultType(rtype2type(typ)) {
+		quoted, err := Marshal(literal)
+		if err != nil {
+			return nil, fmt.Errorf("json: invalid default value of %s: %w", name, err)
+		}
+		src = quoted
+	}
+	return &fieldDefault{typ: typ, dec: dec, name: name, literal: append(src, nul)}, nil
+}
+
+// parse decodes the literal once.
+// It is called for the default values of the fields in the decoded literal as well,
+// so a default value that contains the field itself is reported as an error.
+func (d *fieldDefault) parse() error {
+	if d.value.IsValid() {
+		return nil
+	}
+	if d.parsing {
+		return fmt.Errorf("the value of %s contains the field itself", d.name)
+	}
+	d.parsing = true
+	defer func() { d.parsing = false }()
+	// copy the literal, because decoders may unescape strings in the buffer
+	buf := append([]byte(nil), d.literal...)
+	p := unsafe_New(d.typ)
+	cursor, err := d.dec.decode(&runtimeContext{buf: buf}, 0, 0, p)
+	if err == nil {
+		if cursor = skipWhiteSpace(buf, cursor); buf[cursor] != nul {
+			err = errSyntax("invalid character after the default value", cursor)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("json: invalid default value of %s: %w", d.name, err)
+	}
+	d.value = reflect.NewAt(rtype2type(d.typ), p).Elem()
+	return nil
+}
+
+// apply sets the copy of the default value to the field at p.
+func (d *fieldDefault) apply(p unsafe.Pointer) error {
+	if err := d.parse(); err != nil {
+		return err
+	}
+	copyDefaultValue(reflect.NewAt(rtype2type(d.typ), p).Elem(), d.value)
+	return nil
+}
+
+// copyDefaultValue sets the deep copy of src to dst, so that the decoded values don't share the memory of the default value.
+// The unexported fields are copied as well, because the fields of embedded structs may be decoded through them.
+func copyDefaultValue(dst, src reflect.Value) {
+	typ := src.Type()
+	if isSharableDefaultType(typ) {
+		dst.Set(src)
+		return
+	}
+	switch typ.Kind() {
+	case reflect.Ptr:
+		if src.IsNil() {
+			dst.Set(reflect.Zero(typ))
+			return
+		}
+		v := reflect.New(typ.Elem())
+		copyDefaultValue(v.Elem(), src.Elem())
+		dst.Set(v)
+	case reflect.Slice:
+		if src.IsNil() {
+			dst.Set(reflect.Zero(typ))
+			return
+		}
+		v := reflect.MakeSlice(typ, src.Len(), src.Len())
+		for i := 0; i < src.Len(); i++ {
+			copyDefaultValue(v.Index(i), src.Index(i))
+		}
+		dst.Set(v)
+	case reflect.Array:
+		for i := 0; i < src.Len(); i++ {
+			copyDefaultValue(dst.Index(i), src.Index(i))
+		}
+	case reflect.Map:
+		if src.IsNil() {
+			dst.Set(reflect.Zero(typ))
+			return
+		}
+		v := reflect.MakeMapWithSize(typ, src.Len())
+		iter := src.MapRange()
+		for iter.Next() {
+			key := reflect.New(typ.Key()).Elem()
+			copyDefaultValue(key, addressableValue(iter.Key()))
+			value := reflect.New(typ.Elem()).Elem()
+			copyDefaultValue(value, addressableValue(iter.Value()))
+			v.SetMapIndex(key, value)
+		}
+		dst.Set(v)
+	case reflect.Interface:
+		if src.IsNil() {
+			dst.Set(reflect.Zero(typ))
+			return
+		}
+		elem := src.Elem()
+		v := reflect.New(elem.Type()).Elem()
+		copyDefaultValue(v, addressableValue(elem))
+		dst.Set(v)
+	case reflect.Struct:
+		for i := 0; i < src.NumField(); i++ {
+			copyDefaultValue(settableField(dst, i), settableField(src, i))
+		}
+	default:
+		dst.Set(src)
+	}
+}
+
+// addressableValue returns the addressable copy of v to read its unexported fields.
+func addressableValue(v reflect.Value) reflect.Value {
+	if v.CanAddr() {
+		return v
+	}
+	addressable := reflect.New(v.Type()).Elem()
+	addressable.Set(v)
+	return addressable
+}
+
+// settableField returns the i-th field of the addressable struct v, which can be set even if it is unexported.
+func settableField(v reflect.Value, i int) reflect.Value {
+	field := v.Field(i)
+	return reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem()
+}
+
+// isStringDefaultType reports whether the default value of typ is written as a string without quotes.
+func isStringDefaultType(typ reflect.Type) bool {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ.Kind() == reflect.String
+}
+
+// isSharableDefaultType reports whether the values of typ can be copied without sharing mutable memory.
+func isSharableDefaultType(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex128, reflect.Complex64, reflect.String:
+		return true
+	case reflect.Array:
+		return isSharableDefaultType(typ.Elem())
+	case reflect.Struct:
+		for i := 0; i < typ.NumField(); i++ {
+			if !isSharableDefaultType(typ.Field(i).Type) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
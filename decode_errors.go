@@ -0,0 +1,86 @@
+package json
+
+// collectTypeError reports whether err is collected by DecodeOptionCollectErrors.
+// A collected error is appended to errs, and the caller skips the value to go on decoding.
+func collectTypeError(flag DecodeOptionFlag, errs *DecodeErrors, err error) bool {
+	if flag&DecodeOptionCollectErrors == 0 {
+		return false
+	}
+	typeErr, ok := err.(*UnmarshalTypeError)
+	if !ok {
+		return false
+	}
+	*errs = append(*errs, typeErr)
+	return true
+}
+
+// collectedErrors returns the error of decoding that finished with err after collecting errs.
+func collectedErrors(flag DecodeOptionFlag, errs DecodeErrors, err error) error {
+	if err != nil && !collectTypeError(flag, &errs, err) {
+		return err
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// recoverTypeError collects err and skips the value that starts at cursor.
+// err is returned as is if it isn't collected.
+func (ctx *runtimeContext) recoverTypeError(err error, cursor, depth int64) (int64, error) {
+	if !collectTypeError(ctx.option.Flag, &ctx.errs, err) {
+		return 0, err
+	}
+	return skipValue(ctx.buf, cursor, depth)
+}
+
+// prependCollectedPath adds elem to the beginning of the paths of the errors collected after the first n errors.
+// It is called at every level while unwinding, so it only appends elem to each error, and the paths are formatted by finishErrorPath.
+func (ctx *runtimeContext) prependCollectedPath(n int, elem PathElement) {
+	for _, err := range ctx.errs[n:] {
+		prependErrorPath(err, elem)
+	}
+}
+
+// recoverTypeError collects err and skips the value that starts at the total offset start.
+// err is returned as is if it isn't collected or the beginning of the value has already been discarded from the buffer.
+func (s *stream) recoverTypeError(err error, start, depth int64) error {
+	if start < s.offset || !collectTypeError(s.option.Flag, &s.errs, err) {
+		return err
+	}
+	s.cursor = start - s.offset
+	return s.skipValue(depth)
+}
+
+// prependCollectedPath adds elem to the beginning of the paths of the errors collected after the first n errors.
+func (s *stream) prependCollectedPath(n int, elem PathElement) {
+	for _, err := range s.errs[n:] {
+		prependErrorPath(err, elem)
+	}
+}
+
+// addErrorOffset adds base to the offset of err.
+func addErrorOffset(err error, base int64) error {
+	switch e := err.(type) {
+	case *SyntaxError:
+		e.Offset += base
+	case *UnmarshalTypeError:
+		e.Offset += base
+	case *MissingFieldError:
+		e.Offset += base
+	}
+	return err
+}
+
+// prependElementPath adds the index of an array element to the path of err returned by decoding the element,
+// which starts at the total offset start in buf that begins at the total offset base.
+// A syntax error at a comma or a closing bracket is returned as is, because the array doesn't have the element there.
+func prependElementPath(err error, buf []byte, base, start int64, idx int) error {
+	if serr, ok := err.(*SyntaxError); ok && start >= base {
+		cursor := skipWhiteSpace(buf, start-base)
+		if serr.Offset == base+cursor && (buf[cursor] == ',' || buf[cursor] == ']') {
+			return err
+		}
+	}
+	return prependErrorPath(err, PathElement{Index: idx})
+}
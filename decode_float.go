@@ -146,7 +146,8 @@ func (d *floatDecoder) decodeStream(s *stream, depth int64, p unsafe.Pointer) er
 	return nil
 }
 
-func (d *floatDecoder) decode(buf []byte, cursor, depth int64, p unsafe.Pointer) (int64, error) {
+func (d *floatDecoder) decode(ctx *runtimeContext, cursor, depth int64, p unsafe.Pointer) (int64, error) {
+	buf := ctx.buf
 	bytes, c, err := d.decodeByte(buf, cursor)
 	if err != nil {
 		return 0, err
@@ -2,20 +2,24 @@ package json
 
 import (
 	"bytes"
+	"context"
 	"encoding"
 	"reflect"
 	"unsafe"
 )
 
 type interfaceDecoder struct {
-	typ           *rtype
-	structName    string
-	fieldName     string
-	sliceDecoder  *sliceDecoder
-	mapDecoder    *mapDecoder
-	floatDecoder  *floatDecoder
-	numberDecoder *numberDecoder
-	stringDecoder *stringDecoder
+	typ             *rtype
+	structName      string
+	fieldName       string
+	sliceDecoder    *sliceDecoder
+	mapDecoder      *mapDecoder
+	mapSliceDecoder *mapSliceDecoder
+	floatDecoder    *floatDecoder
+	numberDecoder   *numberDecoder
+	intDecoder      *interfaceIntDecoder
+	stringDecoder   *stringDecoder
+	useMapSlice     bool // decode objects as MapSlice regardless of DecodeOptionUseMapSlice
 }
 
 func newEmptyInterfaceDecoder(structName, fieldName string) *interfaceDecoder {
@@ -29,6 +33,7 @@ func newEmptyInterfaceDecoder(structName, fieldName string) *interfaceDecoder {
 		numberDecoder: newNumberDecoder(structName, fieldName, func(p unsafe.Pointer, v Number) {
 			*(*interface{})(p) = v
 		}),
+		intDecoder:    newInterfaceIntDecoder(structName, fieldName),
 		stringDecoder: newStringDecoder(structName, fieldName),
 	}
 	ifaceDecoder.sliceDecoder = newSliceDecoder(
@@ -46,6 +51,7 @@ func newEmptyInterfaceDecoder(structName, fieldName string) *interfaceDecoder {
 		structName,
 		fieldName,
 	)
+	ifaceDecoder.mapSliceDecoder = newMapSliceDecoder(ifaceDecoder, structName, fieldName)
 	return ifaceDecoder
 }
 
@@ -71,18 +77,23 @@ func newInterfaceDecoder(typ *rtype, structName, fieldName string) *interfaceDec
 			structName,
 			fieldName,
 		),
+		mapSliceDecoder: newMapSliceDecoder(emptyIfaceDecoder, structName, fieldName),
 		floatDecoder: newFloatDecoder(structName, fieldName, func(p unsafe.Pointer, v float64) {
 			*(*interface{})(p) = v
 		}),
 		numberDecoder: newNumberDecoder(structName, fieldName, func(p unsafe.Pointer, v Number) {
 			*(*interface{})(p) = v
 		}),
+		intDecoder:    newInterfaceIntDecoder(structName, fieldName),
 		stringDecoder: stringDecoder,
 	}
 }
 
 func (d *interfaceDecoder) numDecoder(s *stream) decoder {
-	if s.useNumber {
+	if (s.option.Flag & DecodeOptionUseInt64) != 0 {
+		return d.intDecoder
+	}
+	if (s.option.Flag & DecodeOptionUseNumber) != 0 {
 		return d.numberDecoder
 	}
 	return d.floatDecoder
@@ -113,6 +124,25 @@ func decodeStreamUnmarshaler(s *stream, depth int64, unmarshaler Unmarshaler) er
 	return nil
 }
 
+func decodeStreamUnmarshalerContext(s *stream, depth int64, unmarshaler UnmarshalerContext) error {
+	start := s.cursor
+	if err := s.skipValue(depth); err != nil {
+		return err
+	}
+	src := s.buf[start:s.cursor]
+	dst := make([]byte, len(src))
+	copy(dst, src)
+
+	ctx := s.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := unmarshaler.UnmarshalJSON(ctx, dst); err != nil {
+		return err
+	}
+	return nil
+}
+
 func decodeUnmarshaler(buf []byte, cursor, depth int64, unmarshaler Unmarshaler) (int64, error) {
 	cursor = skipWhiteSpace(buf, cursor)
 	start := cursor
@@ -130,6 +160,28 @@ func decodeUnmarshaler(buf []byte, cursor, depth int64, unmarshaler Unmarshaler)
 	return end, nil
 }
 
+func decodeUnmarshalerContext(ctx *runtimeContext, cursor, depth int64, unmarshaler UnmarshalerContext) (int64, error) {
+	buf := ctx.buf
+	cursor = skipWhiteSpace(buf, cursor)
+	start := cursor
+	end, err := skipValue(buf, cursor, depth)
+	if err != nil {
+		return 0, err
+	}
+	src := buf[start:end]
+	dst := make([]byte, len(src))
+	copy(dst, src)
+
+	c := ctx.ctx
+	if c == nil {
+		c = context.Background()
+	}
+	if err := unmarshaler.UnmarshalJSON(c, dst); err != nil {
+		return 0, err
+	}
+	return end, nil
+}
+
 func decodeStreamTextUnmarshaler(s *stream, depth int64, unmarshaler encoding.TextUnmarshaler, p unsafe.Pointer) error {
 	start := s.cursor
 	if err := s.skipValue(depth); err != nil {
@@ -176,6 +228,14 @@ func (d *interfaceDecoder) decodeStreamEmptyInterface(s *stream, depth int64, p
 	for {
 		switch s.char() {
 		case '{':
+			if d.useMapSlice || (s.option.Flag&DecodeOptionUseMapSlice) != 0 {
+				var v MapSlice
+				if err := d.mapSliceDecoder.decodeStream(s, depth, unsafe.Pointer(&v)); err != nil {
+					return err
+				}
+				*(*interface{})(p) = v
+				return nil
+			}
 			var v map[string]interface{}
 			ptr := unsafe.Pointer(&v)
 			if err := d.mapDecoder.decodeStream(s, depth, ptr); err != nil {
@@ -204,6 +264,9 @@ func (d *interfaceDecoder) decodeStreamEmptyInterface(s *stream, depth int64, p
 					}
 				case '"':
 					literal := s.buf[start:s.cursor]
+					if err := s.option.limits.checkString(int64(len(literal)), s.totalOffset()); err != nil {
+						return err
+					}
 					s.cursor++
 					*(*interface{})(p) = string(literal)
 					return nil
@@ -250,6 +313,9 @@ func (d *interfaceDecoder) decodeStream(s *stream, depth int64, p unsafe.Pointer
 	}))
 	rv := reflect.ValueOf(runtimeInterfaceValue)
 	if rv.NumMethod() > 0 && rv.CanInterface() {
+		if u, ok := rv.Interface().(UnmarshalerContext); ok {
+			return decodeStreamUnmarshalerContext(s, depth, u)
+		}
 		if u, ok := rv.Interface().(Unmarshaler); ok {
 			return decodeStreamUnmarshaler(s, depth, u)
 		}
@@ -276,7 +342,7 @@ func (d *interfaceDecoder) decodeStream(s *stream, depth int64, p unsafe.Pointer
 		*(*interface{})(p) = nil
 		return nil
 	}
-	decoder, err := decodeCompileToGetDecoder(typ)
+	decoder, err := decodeCompileToGetDecoderWithConfig(typ, s.option.config)
 	if err != nil {
 		return err
 	}
@@ -293,13 +359,17 @@ func (d *interfaceDecoder) errUnmarshalType(typ reflect.Type, offset int64) *Unm
 	}
 }
 
-func (d *interfaceDecoder) decode(buf []byte, cursor, depth int64, p unsafe.Pointer) (int64, error) {
+func (d *interfaceDecoder) decode(ctx *runtimeContext, cursor, depth int64, p unsafe.Pointer) (int64, error) {
+	buf := ctx.buf
 	runtimeInterfaceValue := *(*interface{})(unsafe.Pointer(&emptyInterface{
 		typ: d.typ,
 		ptr: p,
 	}))
 	rv := reflect.ValueOf(runtimeInterfaceValue)
 	if rv.NumMethod() > 0 && rv.CanInterface() {
+		if u, ok := rv.Interface().(UnmarshalerContext); ok {
+			return decodeUnmarshalerContext(ctx, cursor, depth, u)
+		}
 		if u, ok := rv.Interface().(Unmarshaler); ok {
 			return decodeUnmarshaler(buf, cursor, depth, u)
 		}
@@ -314,10 +384,10 @@ func (d *interfaceDecoder) decode(buf []byte, cursor, depth int64, p unsafe.Poin
 	typ := ifaceHeader.typ
 	if ifaceHeader.ptr == nil || d.typ == typ || typ == nil {
 		// concrete type is empty interface
-		return d.decodeEmptyInterface(buf, cursor, depth, p)
+		return d.decodeEmptyInterface(ctx, cursor, depth, p)
 	}
 	if typ.Kind() == reflect.Ptr && typ.Elem() == d.typ || typ.Kind() != reflect.Ptr {
-		return d.decodeEmptyInterface(buf, cursor, depth, p)
+		return d.decodeEmptyInterface(ctx, cursor, depth, p)
 	}
 	cursor = skipWhiteSpace(buf, cursor)
 	if buf[cursor] == 'n' {
@@ -337,20 +407,30 @@ func (d *interfaceDecoder) decode(buf []byte, cursor, depth int64, p unsafe.Poin
 		**(**interface{})(unsafe.Pointer(&p)) = nil
 		return cursor, nil
 	}
-	decoder, err := decodeCompileToGetDecoder(typ)
+	decoder, err := decodeCompileToGetDecoderWithConfig(typ, ctx.option.config)
 	if err != nil {
 		return 0, err
 	}
-	return decoder.decode(buf, cursor, depth, ifaceHeader.ptr)
+	return decoder.decode(ctx, cursor, depth, ifaceHeader.ptr)
 }
 
-func (d *interfaceDecoder) decodeEmptyInterface(buf []byte, cursor, depth int64, p unsafe.Pointer) (int64, error) {
+func (d *interfaceDecoder) decodeEmptyInterface(ctx *runtimeContext, cursor, depth int64, p unsafe.Pointer) (int64, error) {
+	buf := ctx.buf
 	cursor = skipWhiteSpace(buf, cursor)
 	switch buf[cursor] {
 	case '{':
+		if d.useMapSlice || (ctx.option.Flag&DecodeOptionUseMapSlice) != 0 {
+			var v MapSlice
+			cursor, err := d.mapSliceDecoder.decode(ctx, cursor, depth, unsafe.Pointer(&v))
+			if err != nil {
+				return 0, err
+			}
+			**(**interface{})(unsafe.Pointer(&p)) = v
+			return cursor, nil
+		}
 		var v map[string]interface{}
 		ptr := unsafe.Pointer(&v)
-		cursor, err := d.mapDecoder.decode(buf, cursor, depth, ptr)
+		cursor, err := d.mapDecoder.decode(ctx, cursor, depth, ptr)
 		if err != nil {
 			return 0, err
 		}
@@ -359,18 +439,24 @@ func (d *interfaceDecoder) decodeEmptyInterface(buf []byte, cursor, depth int64,
 	case '[':
 		var v []interface{}
 		ptr := unsafe.Pointer(&v)
-		cursor, err := d.sliceDecoder.decode(buf, cursor, depth, ptr)
+		cursor, err := d.sliceDecoder.decode(ctx, cursor, depth, ptr)
 		if err != nil {
 			return 0, err
 		}
 		**(**interface{})(unsafe.Pointer(&p)) = v
 		return cursor, nil
 	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
-		return d.floatDecoder.decode(buf, cursor, depth, p)
+		if (ctx.option.Flag & DecodeOptionUseInt64) != 0 {
+			return d.intDecoder.decode(ctx, cursor, depth, p)
+		}
+		if (ctx.option.Flag & DecodeOptionUseNumber) != 0 {
+			return d.numberDecoder.decode(ctx, cursor, depth, p)
+		}
+		return d.floatDecoder.decode(ctx, cursor, depth, p)
 	case '"':
 		var v string
 		ptr := unsafe.Pointer(&v)
-		cursor, err := d.stringDecoder.decode(buf, cursor, depth, ptr)
+		cursor, err := d.stringDecoder.decode(ctx, cursor, depth, ptr)
 		if err != nil {
 			return 0, err
 		}
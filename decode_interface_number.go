@@ -0,0 +1,93 @@
+package json
+
+import (
+	"strconv"
+	"unsafe"
+)
+
+// interfaceIntDecoder decodes a number into an interface{} as int64 if it is an integer.
+// The integers that overflow int64 are decoded as uint64,
+// and the other numbers are decoded as Number with UseNumber or float64.
+type interfaceIntDecoder struct {
+	floatDecoder *floatDecoder
+}
+
+func newInterfaceIntDecoder(structName, fieldName string) *interfaceIntDecoder {
+	return &interfaceIntDecoder{
+		floatDecoder: newFloatDecoder(structName, fieldName, nil),
+	}
+}
+
+func (d *interfaceIntDecoder) decodeStream(s *stream, depth int64, p unsafe.Pointer) error {
+	bytes, err := d.floatDecoder.decodeStreamByte(s)
+	if err != nil {
+		return err
+	}
+	if bytes == nil {
+		return nil
+	}
+	if err := d.set(s.option.Flag, bytes, p); err != nil {
+		return errSyntax(err.Error(), s.totalOffset())
+	}
+	return nil
+}
+
+func (d *interfaceIntDecoder) decode(ctx *runtimeContext, cursor, depth int64, p unsafe.Pointer) (int64, error) {
+	buf := ctx.buf
+	bytes, c, err := d.floatDecoder.decodeByte(buf, cursor)
+	if err != nil {
+		return 0, err
+	}
+	if bytes == nil {
+		return c, nil
+	}
+	cursor = c
+	if !validEndNumberChar[buf[cursor]] {
+		return 0, errUnexpectedEndOfJSON("number", cursor)
+	}
+	if err := d.set(ctx.option.Flag, bytes, p); err != nil {
+		return 0, errSyntax(err.Error(), cursor)
+	}
+	return cursor, nil
+}
+
+// set stores the number of b in the interface{} at p.
+func (d *interfaceIntDecoder) set(flag DecodeOptionFlag, b []byte, p unsafe.Pointer) error {
+	s := *(*string)(unsafe.Pointer(&b))
+	if isIntegerBytes(b) {
+		if i64, err := strconv.ParseInt(s, 10, 64); err == nil {
+			*(*interface{})(p) = i64
+			return nil
+		}
+		if u64, err := strconv.ParseUint(s, 10, 64); err == nil {
+			*(*interface{})(p) = u64
+			return nil
+		}
+	}
+	f64, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return err
+	}
+	if (flag & DecodeOptionUseNumber) != 0 {
+		*(*interface{})(p) = Number(string(b))
+		return nil
+	}
+	*(*interface{})(p) = f64
+	return nil
+}
+
+// isIntegerBytes reports whether b is a number literal without a fraction and an exponent.
+func isIntegerBytes(b []byte) bool {
+	if len(b) > 0 && b[0] == '-' {
+		b = b[1:]
+	}
+	if len(b) == 0 {
+		return false
+	}
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,63 @@
+package json
+
+// DecodeLimits restricts the resources consumed by decoding untrusted input.
+// A zero field means no limit, except MaxDepth that defaults to 10000.
+// Each violation is reported by its own error type ( e.g. *DepthLimitError ).
+// Values skipped without being decoded ( e.g. the values of unknown keys ) don't allocate memory,
+// so only MaxBytes and the default depth limit apply to them.
+type DecodeLimits struct {
+	// MaxDepth is the maximum nesting depth of arrays and objects.
+	MaxDepth int64
+	// MaxBytes is the maximum size of the input.
+	// For Decoder, it limits the total bytes read from the underlying reader.
+	MaxBytes int64
+	// MaxStringBytes is the maximum length of a decoded string in bytes.
+	MaxStringBytes int64
+	// MaxElements is the maximum number of elements of an array.
+	MaxElements int64
+	// MaxKeys is the maximum number of keys of an object.
+	MaxKeys int64
+}
+
+// checkDepth returns an error if depth exceeds the limit.
+// A nil *DecodeLimits applies the default depth limit.
+func (l *DecodeLimits) checkDepth(depth int64, c byte, cursor int64) error {
+	if l == nil || l.MaxDepth <= 0 {
+		if depth > maxDecodeNestingDepth {
+			return errExceededMaxDepth(c, cursor)
+		}
+		return nil
+	}
+	if depth > l.MaxDepth {
+		return &DepthLimitError{Limit: l.MaxDepth, Offset: cursor}
+	}
+	return nil
+}
+
+func (l *DecodeLimits) checkBytes(n int64) error {
+	if l != nil && l.MaxBytes > 0 && n > l.MaxBytes {
+		return &BytesLimitError{Limit: l.MaxBytes}
+	}
+	return nil
+}
+
+func (l *DecodeLimits) checkString(n, cursor int64) error {
+	if l != nil && l.MaxStringBytes > 0 && n > l.MaxStringBytes {
+		return &StringLimitError{Limit: l.MaxStringBytes, Offset: cursor}
+	}
+	return nil
+}
+
+func (l *DecodeLimits) checkElements(n, cursor int64) error {
+	if l != nil && l.MaxElements > 0 && n > l.MaxElements {
+		return &ElementsLimitError{Limit: l.MaxElements, Offset: cursor}
+	}
+	return nil
+}
+
+func (l *DecodeLimits) checkKeys(n, cursor int64) error {
+	if l != nil && l.MaxKeys > 0 && n > l.MaxKeys {
+		return &KeysLimitError{Limit: l.MaxKeys, Offset: cursor}
+	}
+	return nil
+}
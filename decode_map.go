@@ -1,6 +1,8 @@
 package json
 
 import (
+	"fmt"
+	"reflect"
 	"unsafe"
 )
 
@@ -33,10 +35,19 @@ func makemap(*rtype, int) unsafe.Pointer
 //go:noescape
 func mapassign(t *rtype, m unsafe.Pointer, key, val unsafe.Pointer)
 
+// pathElement returns the path element of the map value for the decoded key k.
+func (d *mapDecoder) pathElement(k unsafe.Pointer) PathElement {
+	if d.keyType.Kind() == reflect.String {
+		return PathElement{Key: *(*string)(k), Index: -1}
+	}
+	key := reflect.NewAt(rtype2type(d.keyType), k).Elem().Interface()
+	return PathElement{Key: fmt.Sprint(key), Index: -1}
+}
+
 func (d *mapDecoder) decodeStream(s *stream, depth int64, p unsafe.Pointer) error {
 	depth++
-	if depth > maxDecodeNestingDepth {
-		return errExceededMaxDepth(s.char(), s.cursor)
+	if err := s.option.limits.checkDepth(depth, s.char(), s.totalOffset()); err != nil {
+		return err
 	}
 
 	s.skipWhiteSpace()
@@ -61,8 +72,11 @@ func (d *mapDecoder) decodeStream(s *stream, depth int64, p unsafe.Pointer) erro
 		s.cursor += 2
 		return nil
 	}
-	for {
+	for keys := int64(1); ; keys++ {
 		s.cursor++
+		if err := s.option.limits.checkKeys(keys, s.totalOffset()); err != nil {
+			return err
+		}
 		k := unsafe_New(d.keyType)
 		if err := d.keyDecoder.decodeStream(s, depth, k); err != nil {
 			return err
@@ -76,8 +90,14 @@ func (d *mapDecoder) decodeStream(s *stream, depth int64, p unsafe.Pointer) erro
 		}
 		s.cursor++
 		v := unsafe_New(d.valueType)
+		n, start := len(s.errs), s.totalOffset()
 		if err := d.valueDecoder.decodeStream(s, depth, v); err != nil {
-			return err
+			if err := s.recoverTypeError(err, start, depth); err != nil {
+				return prependErrorPath(err, d.pathElement(k))
+			}
+		}
+		if len(s.errs) > n {
+			s.prependCollectedPath(n, d.pathElement(k))
 		}
 		mapassign(d.mapType, mapValue, k, v)
 		s.skipWhiteSpace()
@@ -95,10 +115,11 @@ func (d *mapDecoder) decodeStream(s *stream, depth int64, p unsafe.Pointer) erro
 	}
 }
 
-func (d *mapDecoder) decode(buf []byte, cursor, depth int64, p unsafe.Pointer) (int64, error) {
+func (d *mapDecoder) decode(ctx *runtimeContext, cursor, depth int64, p unsafe.Pointer) (int64, error) {
+	buf := ctx.buf
 	depth++
-	if depth > maxDecodeNestingDepth {
-		return 0, errExceededMaxDepth(buf[cursor], cursor)
+	if err := ctx.option.limits.checkDepth(depth, buf[cursor], cursor); err != nil {
+		return 0, err
 	}
 
 	cursor = skipWhiteSpace(buf, cursor)
@@ -138,9 +159,12 @@ func (d *mapDecoder) decode(buf []byte, cursor, depth int64, p unsafe.Pointer) (
 		cursor++
 		return cursor, nil
 	}
-	for {
+	for keys := int64(1); ; keys++ {
+		if err := ctx.option.limits.checkKeys(keys, cursor); err != nil {
+			return 0, err
+		}
 		k := unsafe_New(d.keyType)
-		keyCursor, err := d.keyDecoder.decode(buf, cursor, depth, k)
+		keyCursor, err := d.keyDecoder.decode(ctx, cursor, depth, k)
 		if err != nil {
 			return 0, err
 		}
@@ -150,9 +174,15 @@ func (d *mapDecoder) decode(buf []byte, cursor, depth int64, p unsafe.Pointer) (
 		}
 		cursor++
 		v := unsafe_New(d.valueType)
-		valueCursor, err := d.valueDecoder.decode(buf, cursor, depth, v)
+		n := len(ctx.errs)
+		valueCursor, err := d.valueDecoder.decode(ctx, cursor, depth, v)
 		if err != nil {
-			return 0, err
+			if valueCursor, err = ctx.recoverTypeError(err, cursor, depth); err != nil {
+				return 0, prependErrorPath(err, d.pathElement(k))
+			}
+		}
+		if len(ctx.errs) > n {
+			ctx.prependCollectedPath(n, d.pathElement(k))
 		}
 		mapassign(d.mapType, mapValue, k, v)
 		cursor = skipWhiteSpace(buf, valueCursor)
@@ -0,0 +1,175 @@
+package json
+
+import "unsafe"
+
+// mapSliceDecoder decodes an object into MapSlice in the order of the document.
+type mapSliceDecoder struct {
+	keyDecoder   *stringDecoder
+	valueDecoder decoder
+	structName   string
+	fieldName    string
+}
+
+func newMapSliceDecoder(valueDec decoder, structName, fieldName string) *mapSliceDecoder {
+	return &mapSliceDecoder{
+		keyDecoder:   newStringDecoder(structName, fieldName),
+		valueDecoder: valueDec,
+		structName:   structName,
+		fieldName:    fieldName,
+	}
+}
+
+func (d *mapSliceDecoder) decodeStream(s *stream, depth int64, p unsafe.Pointer) error {
+	depth++
+	if err := s.option.limits.checkDepth(depth, s.char(), s.totalOffset()); err != nil {
+		return err
+	}
+
+	s.skipWhiteSpace()
+	switch s.char() {
+	case 'n':
+		if err := nullBytes(s); err != nil {
+			return err
+		}
+		*(*MapSlice)(p) = nil
+		return nil
+	case '{':
+	default:
+		return errExpected("{ character for map value", s.totalOffset())
+	}
+	s.cursor++
+	s.skipWhiteSpace()
+	items := (*(*MapSlice)(p))[:0]
+	if items == nil {
+		items = MapSlice{}
+	}
+	if s.char() == '}' {
+		*(*MapSlice)(p) = items
+		s.cursor++
+		return nil
+	}
+	for keys := int64(1); ; keys++ {
+		if err := s.option.limits.checkKeys(keys, s.totalOffset()); err != nil {
+			return err
+		}
+		var key string
+		if err := d.keyDecoder.decodeStream(s, depth, unsafe.Pointer(&key)); err != nil {
+			return err
+		}
+		s.skipWhiteSpace()
+		if s.char() == nul {
+			s.read()
+		}
+		if s.char() != ':' {
+			return errExpected("colon after object key", s.totalOffset())
+		}
+		s.cursor++
+		items = append(items, MapItem{Key: key})
+		v := unsafe.Pointer(&items[len(items)-1].Value)
+		n, start := len(s.errs), s.totalOffset()
+		if err := d.valueDecoder.decodeStream(s, depth, v); err != nil {
+			if err := s.recoverTypeError(err, start, depth); err != nil {
+				return prependErrorPath(err, PathElement{Key: key, Index: -1})
+			}
+		}
+		if len(s.errs) > n {
+			s.prependCollectedPath(n, PathElement{Key: key, Index: -1})
+		}
+		s.skipWhiteSpace()
+		if s.char() == nul {
+			s.read()
+		}
+		if s.char() == '}' {
+			*(*MapSlice)(p) = items
+			s.cursor++
+			return nil
+		}
+		if s.char() != ',' {
+			return errExpected("comma after object value", s.totalOffset())
+		}
+		s.cursor++
+	}
+}
+
+func (d *mapSliceDecoder) decode(ctx *runtimeContext, cursor, depth int64, p unsafe.Pointer) (int64, error) {
+	buf := ctx.buf
+	depth++
+	if err := ctx.option.limits.checkDepth(depth, buf[cursor], cursor); err != nil {
+		return 0, err
+	}
+
+	cursor = skipWhiteSpace(buf, cursor)
+	buflen := int64(len(buf))
+	if buflen < 2 {
+		return 0, errExpected("{} for map", cursor)
+	}
+	switch buf[cursor] {
+	case 'n':
+		if cursor+3 >= buflen {
+			return 0, errUnexpectedEndOfJSON("null", cursor)
+		}
+		if buf[cursor+1] != 'u' {
+			return 0, errInvalidCharacter(buf[cursor+1], "null", cursor)
+		}
+		if buf[cursor+2] != 'l' {
+			return 0, errInvalidCharacter(buf[cursor+2], "null", cursor)
+		}
+		if buf[cursor+3] != 'l' {
+			return 0, errInvalidCharacter(buf[cursor+3], "null", cursor)
+		}
+		cursor += 4
+		*(*MapSlice)(p) = nil
+		return cursor, nil
+	case '{':
+	default:
+		return 0, errExpected("{ character for map value", cursor)
+	}
+	cursor++
+	cursor = skipWhiteSpace(buf, cursor)
+	items := (*(*MapSlice)(p))[:0]
+	if items == nil {
+		items = MapSlice{}
+	}
+	if buf[cursor] == '}' {
+		*(*MapSlice)(p) = items
+		cursor++
+		return cursor, nil
+	}
+	for keys := int64(1); ; keys++ {
+		if err := ctx.option.limits.checkKeys(keys, cursor); err != nil {
+			return 0, err
+		}
+		var key string
+		keyCursor, err := d.keyDecoder.decode(ctx, cursor, depth, unsafe.Pointer(&key))
+		if err != nil {
+			return 0, err
+		}
+		cursor = skipWhiteSpace(buf, keyCursor)
+		if buf[cursor] != ':' {
+			return 0, errExpected("colon after object key", cursor)
+		}
+		cursor++
+		items = append(items, MapItem{Key: key})
+		v := unsafe.Pointer(&items[len(items)-1].Value)
+		n := len(ctx.errs)
+		valueCursor, err := d.valueDecoder.decode(ctx, cursor, depth, v)
+		if err != nil {
+			if valueCursor, err = ctx.recoverTypeError(err, cursor, depth); err != nil {
+				return 0, prependErrorPath(err, PathElement{Key: key, Index: -1})
+			}
+		}
+		if len(ctx.errs) > n {
+			ctx.prependCollectedPath(n, PathElement{Key: key, Index: -1})
+		}
+		cursor = skipWhiteSpace(buf, valueCursor)
+		if buf[cursor] == '}' {
+			*(*MapSlice)(p) = items
+			cursor++
+			return cursor, nil
+		}
+		if buf[cursor] != ',' {
+			return 0, errExpected("comma after object value", cursor)
+		}
+		cursor++
+	}
+}
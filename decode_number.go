@@ -34,7 +34,8 @@ func (d *numberDecoder) decodeStream(s *stream, depth int64, p unsafe.Pointer) e
 	return nil
 }
 
-func (d *numberDecoder) decode(buf []byte, cursor, depth int64, p unsafe.Pointer) (int64, error) {
+func (d *numberDecoder) decode(ctx *runtimeContext, cursor, depth int64, p unsafe.Pointer) (int64, error) {
+	buf := ctx.buf
 	bytes, c, err := d.decodeByte(buf, cursor)
 	if err != nil {
 		return 0, err
@@ -109,7 +110,7 @@ func (d *numberDecoder) decodeByte(buf []byte, cursor int64) ([]byte, int64, err
 			cursor += 4
 			return nil, cursor, nil
 		case '"':
-			return d.stringDecoder.decodeByte(buf, cursor)
+			return d.stringDecoder.decodeByteCopy(buf, cursor)
 		default:
 			return nil, 0, errUnexpectedEndOfJSON("json.Number", cursor)
 		}
@@ -57,7 +57,8 @@ func (d *ptrDecoder) decodeStream(s *stream, depth int64, p unsafe.Pointer) erro
 	return nil
 }
 
-func (d *ptrDecoder) decode(buf []byte, cursor, depth int64, p unsafe.Pointer) (int64, error) {
+func (d *ptrDecoder) decode(ctx *runtimeContext, cursor, depth int64, p unsafe.Pointer) (int64, error) {
+	buf := ctx.buf
 	cursor = skipWhiteSpace(buf, cursor)
 	if buf[cursor] == 'n' {
 		buflen := int64(len(buf))
@@ -86,7 +87,7 @@ func (d *ptrDecoder) decode(buf []byte, cursor, depth int64, p unsafe.Pointer) (
 	} else {
 		newptr = *(*unsafe.Pointer)(p)
 	}
-	c, err := d.dec.decode(buf, cursor, depth, newptr)
+	c, err := d.dec.decode(ctx, cursor, depth, newptr)
 	if err != nil {
 		return 0, err
 	}
@@ -77,8 +77,8 @@ func (d *sliceDecoder) errNumber(offset int64) *UnmarshalTypeError {
 
 func (d *sliceDecoder) decodeStream(s *stream, depth int64, p unsafe.Pointer) error {
 	depth++
-	if depth > maxDecodeNestingDepth {
-		return errExceededMaxDepth(s.char(), s.cursor)
+	if err := s.option.limits.checkDepth(depth, s.char(), s.totalOffset()); err != nil {
+		return err
 	}
 
 	for {
@@ -120,9 +120,13 @@ func (d *sliceDecoder) decodeStream(s *stream, depth int64, p unsafe.Pointer) er
 				if d.isElemPointerType {
 					*(*unsafe.Pointer)(ep) = nil // initialize elem pointer
 				}
+				n, start := len(s.errs), s.totalOffset()
 				if err := d.valueDecoder.decodeStream(s, depth, ep); err != nil {
-					return err
+					if err := s.recoverTypeError(err, start, depth); err != nil {
+						return prependElementPath(err, s.buf, s.offset, start, idx)
+					}
 				}
+				s.prependCollectedPath(n, PathElement{Index: idx})
 				s.skipWhiteSpace()
 			RETRY:
 				switch s.char() {
@@ -147,6 +151,12 @@ func (d *sliceDecoder) decodeStream(s *stream, depth int64, p unsafe.Pointer) er
 					return nil
 				case ',':
 					idx++
+					if err := s.option.limits.checkElements(int64(idx+1), s.totalOffset()); err != nil {
+						slice.cap = capacity
+						slice.data = data
+						d.releaseSlice(slice)
+						return err
+					}
 				case nul:
 					if s.read() {
 						goto RETRY
@@ -178,10 +188,11 @@ ERROR:
 	return errUnexpectedEndOfJSON("slice", s.totalOffset())
 }
 
-func (d *sliceDecoder) decode(buf []byte, cursor, depth int64, p unsafe.Pointer) (int64, error) {
+func (d *sliceDecoder) decode(ctx *runtimeContext, cursor, depth int64, p unsafe.Pointer) (int64, error) {
+	buf := ctx.buf
 	depth++
-	if depth > maxDecodeNestingDepth {
-		return 0, errExceededMaxDepth(buf[cursor], cursor)
+	if err := ctx.option.limits.checkDepth(depth, buf[cursor], cursor); err != nil {
+		return 0, err
 	}
 
 	buflen := int64(len(buf))
@@ -234,10 +245,14 @@ func (d *sliceDecoder) decode(buf []byte, cursor, depth int64, p unsafe.Pointer)
 				if d.isElemPointerType {
 					*(*unsafe.Pointer)(ep) = nil // initialize elem pointer
 				}
-				c, err := d.valueDecoder.decode(buf, cursor, depth, ep)
+				n := len(ctx.errs)
+				c, err := d.valueDecoder.decode(ctx, cursor, depth, ep)
 				if err != nil {
-					return 0, err
+					if c, err = ctx.recoverTypeError(err, cursor, depth); err != nil {
+						return 0, prependElementPath(err, buf, 0, cursor, idx)
+					}
 				}
+				ctx.prependCollectedPath(n, PathElement{Index: idx})
 				cursor = c
 				cursor = skipWhiteSpace(buf, cursor)
 				switch buf[cursor] {
@@ -262,6 +277,12 @@ func (d *sliceDecoder) decode(buf []byte, cursor, depth int64, p unsafe.Pointer)
 					return cursor, nil
 				case ',':
 					idx++
+					if err := ctx.option.limits.checkElements(int64(idx+1), cursor); err != nil {
+						slice.cap = capacity
+						slice.data = data
+						d.releaseSlice(slice)
+						return 0, err
+					}
 				default:
 					slice.cap = capacity
 					slice.data = data
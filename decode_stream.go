@@ -2,24 +2,37 @@ package json
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"unsafe"
 )
 
 const (
-	initBufSize = 512
+	initBufSize    = 512
+	maxLineHeadLen = 80 // maximum length of the beginning of the current line kept by reset
 )
 
 type stream struct {
-	buf                   []byte
-	bufSize               int64
-	length                int64
-	r                     io.Reader
-	offset                int64
-	cursor                int64
-	allRead               bool
-	useNumber             bool
-	disallowUnknownFields bool
+	buf     []byte
+	bufSize int64
+	length  int64
+	r       io.Reader
+	offset  int64
+	cursor  int64
+	allRead bool
+	option  DecodeOption
+	ctx     context.Context
+
+	readBytes int64 // total bytes read from r
+	limitErr  error // error of DecodeLimits.MaxBytes that stopped reading
+
+	errs      DecodeErrors            // errors collected by DecodeOptionCollectErrors
+	positions map[int64]errorPosition // positions of the collected errors by their offsets
+	located   int                     // number of the collected errors whose positions are kept
+
+	lines     int   // number of newlines before the cursor at the last reset
+	lineStart int64 // offset of the beginning of the line of the cursor at the last reset
+	kept      int64 // length of the beginning of buf kept by reset, which has no newline
 }
 
 func newStream(r io.Reader) *stream {
@@ -52,10 +65,76 @@ func (s *stream) stat() ([]byte, int64, unsafe.Pointer) {
 	return s.buf, s.cursor, (*sliceHeader)(unsafe.Pointer(&s.buf)).data
 }
 
+// source returns the part of the input that remains in the buffer to locate the offset of an error.
+func (s *stream) source() errorSource {
+	buf := s.buf
+	if end := bytes.IndexByte(buf, nul); end >= 0 {
+		buf = buf[:end]
+	}
+	return errorSource{
+		Buf:       buf,
+		Base:      s.offset,
+		Line:      s.lines + 1,
+		LineStart: s.lineStart,
+	}
+}
+
+// locateError returns the position of err in the input.
+// The collected errors are located by the positions kept for them, and the other errors by the buffer.
+func (s *stream) locateError(err error) (errorPosition, bool) {
+	if offset, ok := errorOffset(err); ok {
+		if pos, exists := s.positions[offset]; exists {
+			return pos, true
+		}
+	}
+	return locateError(s.source(), err)
+}
+
+// keepErrorPositions keeps the positions of the errors collected since the last call,
+// because their lines are discarded from the buffer by reset.
+func (s *stream) keepErrorPositions() {
+	if s.located == len(s.errs) {
+		return
+	}
+	src := s.source()
+	for _, err := range s.errs[s.located:] {
+		offset, ok := errorOffset(err)
+		if !ok {
+			continue
+		}
+		if pos, ok := src.Locate(offset); ok {
+			if s.positions == nil {
+				s.positions = map[int64]errorPosition{}
+			}
+			s.positions[offset] = pos
+		}
+	}
+	s.located = len(s.errs)
+}
+
+// reset discards the bytes before the cursor from the buffer.
+// The beginning of the current line up to maxLineHeadLen bytes is kept to render errors.
 func (s *stream) reset() {
-	s.offset += s.cursor
-	s.buf = s.buf[s.cursor:]
-	s.cursor = 0
+	if s.cursor <= s.kept {
+		return
+	}
+	s.keepErrorPositions()
+	read := s.buf[s.kept:s.cursor]
+	if i := bytes.LastIndexByte(read, '\n'); i >= 0 {
+		s.lines += bytes.Count(read, []byte{'\n'})
+		s.lineStart = s.offset + s.kept + int64(i) + 1
+	}
+	from := s.lineStart - s.offset
+	if head := s.cursor - maxLineHeadLen; from < head {
+		from = head
+	}
+	if from < 0 {
+		from = 0
+	}
+	s.offset += from
+	s.buf = s.buf[from:]
+	s.cursor -= from
+	s.kept = s.cursor
 	s.length = int64(len(s.buf))
 }
 
@@ -71,11 +150,31 @@ func (s *stream) read() bool {
 	if s.allRead {
 		return false
 	}
+	if s.ctx != nil {
+		select {
+		case <-s.ctx.Done():
+			return false
+		default:
+		}
+	}
 	buf := s.readBuf()
 	last := len(buf) - 1
 	buf[last] = nul
+	if l := s.option.limits; l != nil && l.MaxBytes > 0 {
+		// read one more byte than the limit to detect the violation
+		if remain := l.MaxBytes - s.readBytes + 1; int64(last) > remain {
+			last = int(remain)
+			buf[last] = nul
+		}
+	}
 	n, err := s.r.Read(buf[:last])
 	s.length = s.cursor + int64(n)
+	s.readBytes += int64(n)
+	if err := s.option.limits.checkBytes(s.readBytes); err != nil {
+		s.limitErr = err
+		s.allRead = true
+		return false
+	}
 	if err == io.EOF {
 		s.allRead = true
 	} else if err != nil {
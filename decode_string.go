@@ -38,19 +38,25 @@ func (d *stringDecoder) decodeStream(s *stream, depth int64, p unsafe.Pointer) e
 	if bytes == nil {
 		return nil
 	}
+	if err := s.option.limits.checkString(int64(len(bytes)), s.totalOffset()); err != nil {
+		return err
+	}
 	**(**string)(unsafe.Pointer(&p)) = *(*string)(unsafe.Pointer(&bytes))
 	s.reset()
 	return nil
 }
 
-func (d *stringDecoder) decode(buf []byte, cursor, depth int64, p unsafe.Pointer) (int64, error) {
-	bytes, c, err := d.decodeByte(buf, cursor)
+func (d *stringDecoder) decode(ctx *runtimeContext, cursor, depth int64, p unsafe.Pointer) (int64, error) {
+	bytes, c, err := d.decodeContextByte(ctx, cursor)
 	if err != nil {
 		return 0, err
 	}
 	if bytes == nil {
 		return c, nil
 	}
+	if err := ctx.option.limits.checkString(int64(len(bytes)), cursor); err != nil {
+		return 0, err
+	}
 	cursor = c
 	**(**string)(unsafe.Pointer(&p)) = *(*string)(unsafe.Pointer(&bytes))
 	return cursor, nil
@@ -372,3 +378,52 @@ func (d *stringDecoder) decodeByte(buf []byte, cursor int64) ([]byte, int64, err
 ERROR:
 	return nil, 0, errNotAtBeginningOfValue(cursor)
 }
+
+// decodeContextByte decodes the string at cursor of ctx.buf.
+// The buffer borrowed by UnmarshalBorrow is not modified.
+func (d *stringDecoder) decodeContextByte(ctx *runtimeContext, cursor int64) ([]byte, int64, error) {
+	if ctx.option.Flag&DecodeOptionBorrow != 0 {
+		return d.decodeByteCopy(ctx.buf, cursor)
+	}
+	return d.decodeByte(ctx.buf, cursor)
+}
+
+// decodeByteCopy is decodeByte that doesn't modify buf.
+// A string without escape sequences is returned as a slice of buf, and a string that has them is unescaped in a copy.
+func (d *stringDecoder) decodeByteCopy(buf []byte, cursor int64) ([]byte, int64, error) {
+	cursor = skipWhiteSpace(buf, cursor)
+	if buf[cursor] != '"' {
+		return d.decodeByte(buf, cursor)
+	}
+	b := (*sliceHeader)(unsafe.Pointer(&buf)).data
+	escaped := false
+	end := cursor + 1
+	for {
+		switch char(b, end) {
+		case '\\':
+			escaped = true
+			end++
+			if char(b, end) == nul {
+				return nil, 0, errUnexpectedEndOfJSON("string", end)
+			}
+		case '"':
+			end++
+			if !escaped {
+				return buf[cursor+1 : end-1], end, nil
+			}
+			src := make([]byte, end-cursor+1) // append nul byte to the end
+			copy(src, buf[cursor:end])
+			literal, _, err := d.decodeByte(src, 0)
+			if err != nil {
+				if serr, ok := err.(*SyntaxError); ok {
+					serr.Offset += cursor
+				}
+				return nil, 0, err
+			}
+			return literal, end, nil
+		case nul:
+			return nil, 0, errUnexpectedEndOfJSON("string", end)
+		}
+		end++
+	}
+}
@@ -16,6 +16,25 @@ type structFieldSet struct {
 	key         string
 	keyLen      int64
 	err         error
+	tracked     trackedField
+}
+
+// trackedField is the field whose presence in an object is tracked because it is required or has the default value.
+type trackedField struct {
+	key          string // the key of the field, or empty if the field isn't tracked
+	idx          int    // index of the field in structDecoder.trackedFields
+	isRequired   bool
+	defaultValue *fieldDefault
+}
+
+// withoutDefault returns t without the default value.
+// It is used for the fields of a struct embedded by a pointer, because the pointer is allocated only when the fields are decoded.
+func (t trackedField) withoutDefault() trackedField {
+	if !t.isRequired {
+		return trackedField{}
+	}
+	t.defaultValue = nil
+	return t
 }
 
 type structDecoder struct {
@@ -24,15 +43,73 @@ type structDecoder struct {
 	structName       string
 	fieldName        string
 	isTriedOptimize  bool
+	caseSensitive    bool
+	keyCharTable     *[256]byte
 	keyBitmapUint8   [][256]uint8
 	keyBitmapUint16  [][256]uint16
 	sortedFieldSets  []*structFieldSet
-	keyDecoder       func(*structDecoder, []byte, int64) (int64, *structFieldSet, error)
+	keyDecoder       func(*structDecoder, *runtimeContext, int64) (int64, *structFieldSet, error)
 	keyStreamDecoder func(*structDecoder, *stream) (*structFieldSet, string, error)
+	inlineField      *inlineFieldDecoder
+	typeName         string
+	trackedFields    []*structFieldSet
+}
+
+// inlineFieldDecoder decodes the values of the unknown keys into the map field that has the inline option.
+// If the map field is in a struct embedded by a pointer, offset is the offset of the pointer
+// and embedded is the inline field of the struct.
+type inlineFieldDecoder struct {
+	offset     uintptr
+	dec        *mapDecoder
+	structType *rtype              // the type of the struct embedded by the pointer
+	embedded   *inlineFieldDecoder // the inline field of the struct embedded by the pointer
+}
+
+func (d *inlineFieldDecoder) mapValue(p unsafe.Pointer) unsafe.Pointer {
+	if d.embedded != nil {
+		sp := (*unsafe.Pointer)(unsafe.Pointer(uintptr(p) + d.offset))
+		if *sp == nil {
+			*sp = unsafe_New(d.structType)
+		}
+		return d.embedded.mapValue(*sp)
+	}
+	mp := (*unsafe.Pointer)(unsafe.Pointer(uintptr(p) + d.offset))
+	if *mp == nil {
+		*mp = makemap(d.dec.mapType, 0)
+	}
+	return *mp
+}
+
+func (d *inlineFieldDecoder) decodeStream(s *stream, depth int64, key string, p unsafe.Pointer) error {
+	v := unsafe_New(d.dec.valueType)
+	n, start := len(s.errs), s.totalOffset()
+	if err := d.dec.valueDecoder.decodeStream(s, depth, v); err != nil {
+		if err := s.recoverTypeError(err, start, depth); err != nil {
+			return prependErrorPath(err, PathElement{Key: key, Index: -1})
+		}
+	}
+	s.prependCollectedPath(n, PathElement{Key: key, Index: -1})
+	mapassign(d.dec.mapType, d.mapValue(p), unsafe.Pointer(&key), v)
+	return nil
+}
+
+func (d *inlineFieldDecoder) decode(ctx *runtimeContext, cursor, depth int64, key string, p unsafe.Pointer) (int64, error) {
+	v := unsafe_New(d.dec.valueType)
+	n := len(ctx.errs)
+	c, err := d.dec.valueDecoder.decode(ctx, cursor, depth, v)
+	if err != nil {
+		if c, err = ctx.recoverTypeError(err, cursor, depth); err != nil {
+			return 0, prependErrorPath(err, PathElement{Key: key, Index: -1})
+		}
+	}
+	ctx.prependCollectedPath(n, PathElement{Key: key, Index: -1})
+	mapassign(d.dec.mapType, d.mapValue(p), unsafe.Pointer(&key), v)
+	return c, nil
 }
 
 var (
 	largeToSmallTable [256]byte
+	identityTable     [256]byte
 )
 
 func init() {
@@ -42,6 +119,7 @@ func init() {
 			c += 'a' - 'A'
 		}
 		largeToSmallTable[i] = byte(c)
+		identityTable[i] = byte(i)
 	}
 }
 
@@ -51,11 +129,88 @@ func newStructDecoder(structName, fieldName string, fieldMap map[string]*structF
 		stringDecoder:    newStringDecoder(structName, fieldName),
 		structName:       structName,
 		fieldName:        fieldName,
+		keyCharTable:     &largeToSmallTable,
 		keyDecoder:       decodeKey,
 		keyStreamDecoder: decodeKeyStream,
 	}
 }
 
+// indexTrackedFields assigns the index of the bitmap of the decoded keys to the fields whose presence is tracked.
+// The field sets of the lower case keys share the index with the field sets of the original keys.
+func (d *structDecoder) indexTrackedFields() {
+	keys := make([]string, 0, len(d.fieldMap))
+	for k := range d.fieldMap {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	indexes := map[string]int{}
+	for _, k := range keys {
+		field := d.fieldMap[k]
+		if field.tracked.key == "" {
+			continue
+		}
+		idx, exists := indexes[field.tracked.key]
+		if !exists {
+			idx = len(d.trackedFields)
+			indexes[field.tracked.key] = idx
+			d.trackedFields = append(d.trackedFields, field)
+		}
+		field.tracked.idx = idx
+	}
+}
+
+// decodedBitmap returns the bitmap to mark the decoded tracked fields of an object.
+// buf is used if it's large enough, so that the bitmap of up to 64 fields can be kept on the stack of the caller.
+// It returns nil if the struct has no tracked field.
+func (d *structDecoder) decodedBitmap(buf []uint64) []uint64 {
+	if len(d.trackedFields) == 0 {
+		return nil
+	}
+	if n := (len(d.trackedFields) + 63) / 64; n > len(buf) {
+		return make([]uint64, n)
+	}
+	return buf
+}
+
+func markDecodedField(decoded []uint64, field *structFieldSet) {
+	if decoded == nil || field.tracked.key == "" {
+		return
+	}
+	decoded[field.tracked.idx/64] |= 1 << uint(field.tracked.idx%64)
+}
+
+// finishObject checks the tracked fields that are not in decoded after decoding an object at offset.
+// It returns MissingFieldError if the object doesn't have some of the required fields,
+// or appends it to errs with DecodeOptionCollectErrors and goes on.
+// The default values are applied to the other absent fields.
+func (d *structDecoder) finishObject(decoded []uint64, offset int64, opt DecodeOption, errs *DecodeErrors, p unsafe.Pointer) error {
+	if decoded == nil {
+		return nil
+	}
+	var missing []string
+	for i, field := range d.trackedFields {
+		if decoded[i/64]&(1<<uint(i%64)) == 0 && field.tracked.isRequired {
+			missing = append(missing, field.tracked.key)
+		}
+	}
+	if len(missing) > 0 {
+		err := &MissingFieldError{Struct: d.typeName, Keys: missing, Offset: offset}
+		if opt.Flag&DecodeOptionCollectErrors == 0 {
+			return err
+		}
+		*errs = append(*errs, err)
+	}
+	for i, field := range d.trackedFields {
+		if decoded[i/64]&(1<<uint(i%64)) != 0 || field.tracked.defaultValue == nil {
+			continue
+		}
+		if err := field.tracked.defaultValue.apply(unsafe.Pointer(uintptr(p) + field.offset)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 const (
 	allowOptimizeMaxKeyLen   = 64
 	allowOptimizeMaxFieldLen = 16
@@ -65,6 +220,12 @@ func (d *structDecoder) tryOptimize() {
 	if d.isTriedOptimize {
 		return
 	}
+	if d.caseSensitive {
+		// the bitmap is built from the exact keys and matched without folding the case of the input
+		d.keyCharTable = &identityTable
+		d.buildKeyBitmap(d.fieldMap)
+		return
+	}
 	fieldMap := map[string]*structFieldSet{}
 	conflicted := map[string]struct{}{}
 	for k, v := range d.fieldMap {
@@ -85,7 +246,10 @@ func (d *structDecoder) tryOptimize() {
 		}
 		fieldMap[key] = v
 	}
+	d.buildKeyBitmap(fieldMap)
+}
 
+func (d *structDecoder) buildKeyBitmap(fieldMap map[string]*structFieldSet) {
 	if len(fieldMap) > allowOptimizeMaxFieldLen {
 		d.isTriedOptimize = true
 		return
@@ -136,7 +300,8 @@ func (d *structDecoder) tryOptimize() {
 	}
 }
 
-func decodeKeyByBitmapUint8(d *structDecoder, buf []byte, cursor int64) (int64, *structFieldSet, error) {
+func decodeKeyByBitmapUint8(d *structDecoder, ctx *runtimeContext, cursor int64) (int64, *structFieldSet, error) {
+	buf := ctx.buf
 	var (
 		field  *structFieldSet
 		curBit uint8 = math.MaxUint8
@@ -158,6 +323,7 @@ func decodeKeyByBitmapUint8(d *structDecoder, buf []byte, cursor int64) (int64,
 			}
 			keyIdx := 0
 			bitmap := d.keyBitmapUint8
+			table := d.keyCharTable
 			start := cursor
 			for {
 				c := char(b, cursor)
@@ -175,7 +341,7 @@ func decodeKeyByBitmapUint8(d *structDecoder, buf []byte, cursor int64) (int64,
 				case nul:
 					return 0, nil, errUnexpectedEndOfJSON("string", cursor)
 				default:
-					curBit &= bitmap[keyIdx][largeToSmallTable[c]]
+					curBit &= bitmap[keyIdx][table[c]]
 					if curBit == 0 {
 						for {
 							cursor++
@@ -203,7 +369,8 @@ func decodeKeyByBitmapUint8(d *structDecoder, buf []byte, cursor int64) (int64,
 	}
 }
 
-func decodeKeyByBitmapUint16(d *structDecoder, buf []byte, cursor int64) (int64, *structFieldSet, error) {
+func decodeKeyByBitmapUint16(d *structDecoder, ctx *runtimeContext, cursor int64) (int64, *structFieldSet, error) {
+	buf := ctx.buf
 	var (
 		field  *structFieldSet
 		curBit uint16 = math.MaxUint16
@@ -225,6 +392,7 @@ func decodeKeyByBitmapUint16(d *structDecoder, buf []byte, cursor int64) (int64,
 			}
 			keyIdx := 0
 			bitmap := d.keyBitmapUint16
+			table := d.keyCharTable
 			start := cursor
 			for {
 				c := char(b, cursor)
@@ -242,7 +410,7 @@ func decodeKeyByBitmapUint16(d *structDecoder, buf []byte, cursor int64) (int64,
 				case nul:
 					return 0, nil, errUnexpectedEndOfJSON("string", cursor)
 				default:
-					curBit &= bitmap[keyIdx][largeToSmallTable[c]]
+					curBit &= bitmap[keyIdx][table[c]]
 					if curBit == 0 {
 						for {
 							cursor++
@@ -270,8 +438,8 @@ func decodeKeyByBitmapUint16(d *structDecoder, buf []byte, cursor int64) (int64,
 	}
 }
 
-func decodeKey(d *structDecoder, buf []byte, cursor int64) (int64, *structFieldSet, error) {
-	key, c, err := d.stringDecoder.decodeByte(buf, cursor)
+func decodeKey(d *structDecoder, ctx *runtimeContext, cursor int64) (int64, *structFieldSet, error) {
+	key, c, err := d.stringDecoder.decodeContextByte(ctx, cursor)
 	if err != nil {
 		return 0, nil, err
 	}
@@ -284,6 +452,57 @@ func decodeKey(d *structDecoder, buf []byte, cursor int64) (int64, *structFieldS
 	return cursor, field, nil
 }
 
+// foldedFieldSet returns the field that matches key by case-insensitive matching.
+// It is used to find the field of a key that isn't matched by decodeKey or decodeKeyStream as they are.
+func (d *structDecoder) foldedFieldSet(key string) *structFieldSet {
+	if d.caseSensitive {
+		return nil
+	}
+	return d.fieldMap[strings.ToLower(key)]
+}
+
+// decodeInlineKey decodes the key at cursor of the struct that has the inline field.
+// The key that doesn't match a field is returned to store it into the inline field.
+func (d *structDecoder) decodeInlineKey(ctx *runtimeContext, cursor int64) (int64, *structFieldSet, string, error) {
+	if d.sortedFieldSets != nil {
+		// the bitmap doesn't return the key, but leaves the buffer as it is to decode the unknown key again
+		c, field, err := d.keyDecoder(d, ctx, cursor)
+		if err != nil || field != nil {
+			return c, field, "", err
+		}
+	}
+	// the key is decoded only once by the decoder of strings because it may be unescaped in the buffer
+	key, c, err := d.stringDecoder.decodeContextByte(ctx, cursor)
+	if err != nil {
+		return 0, nil, "", err
+	}
+	if field, exists := d.fieldMap[*(*string)(unsafe.Pointer(&key))]; exists {
+		return c, field, "", nil
+	}
+	inlineKey := string(key)
+	return c, d.foldedFieldSet(inlineKey), inlineKey, nil
+}
+
+// decodeInlineKeyStream decodes the key of the struct that has the inline field in the same way as decodeInlineKey.
+func (d *structDecoder) decodeInlineKeyStream(s *stream) (*structFieldSet, string, error) {
+	if d.sortedFieldSets != nil {
+		// the key stays in the buffer until the stream is reset for the next key
+		start := s.cursor
+		field, _, err := d.keyStreamDecoder(d, s)
+		if err != nil || field != nil {
+			return field, "", err
+		}
+		s.cursor = start
+	}
+	field, key, err := decodeKeyStream(d, s)
+	if err != nil || field != nil {
+		return field, "", err
+	}
+	// copy the key because it refers to the buffer of the stream
+	key = string([]byte(key))
+	return d.foldedFieldSet(key), key, nil
+}
+
 func decodeKeyByBitmapUint8Stream(d *structDecoder, s *stream) (*structFieldSet, string, error) {
 	var (
 		field  *structFieldSet
@@ -320,6 +539,7 @@ func decodeKeyByBitmapUint8Stream(d *structDecoder, s *stream) (*structFieldSet,
 			}
 			keyIdx := 0
 			bitmap := d.keyBitmapUint8
+			table := d.keyCharTable
 			for {
 				c := char(p, cursor)
 				switch c {
@@ -342,7 +562,7 @@ func decodeKeyByBitmapUint8Stream(d *structDecoder, s *stream) (*structFieldSet,
 					}
 					return nil, "", errUnexpectedEndOfJSON("string", s.totalOffset())
 				default:
-					curBit &= bitmap[keyIdx][largeToSmallTable[c]]
+					curBit &= bitmap[keyIdx][table[c]]
 					if curBit == 0 {
 						for {
 							cursor++
@@ -417,6 +637,7 @@ func decodeKeyByBitmapUint16Stream(d *structDecoder, s *stream) (*structFieldSet
 			}
 			keyIdx := 0
 			bitmap := d.keyBitmapUint16
+			table := d.keyCharTable
 			for {
 				c := char(p, cursor)
 				switch c {
@@ -439,7 +660,7 @@ func decodeKeyByBitmapUint16Stream(d *structDecoder, s *stream) (*structFieldSet
 					}
 					return nil, "", errUnexpectedEndOfJSON("string", s.totalOffset())
 				default:
-					curBit &= bitmap[keyIdx][largeToSmallTable[c]]
+					curBit &= bitmap[keyIdx][table[c]]
 					if curBit == 0 {
 						for {
 							cursor++
@@ -489,8 +710,8 @@ func decodeKeyStream(d *structDecoder, s *stream) (*structFieldSet, string, erro
 
 func (d *structDecoder) decodeStream(s *stream, depth int64, p unsafe.Pointer) error {
 	depth++
-	if depth > maxDecodeNestingDepth {
-		return errExceededMaxDepth(s.char(), s.cursor)
+	if err := s.option.limits.checkDepth(depth, s.char(), s.totalOffset()); err != nil {
+		return err
 	}
 
 	s.skipWhiteSpace()
@@ -509,13 +730,30 @@ func (d *structDecoder) decodeStream(s *stream, depth int64, p unsafe.Pointer) e
 	}
 	s.cursor++
 	s.skipWhiteSpace()
+	var bitmap [1]uint64
+	decoded := d.decodedBitmap(bitmap[:])
 	if s.char() == '}' {
+		if err := d.finishObject(decoded, s.totalOffset(), s.option, &s.errs, p); err != nil {
+			return err
+		}
 		s.cursor++
 		return nil
 	}
-	for {
+	for keys := int64(1); ; keys++ {
 		s.reset()
-		field, key, err := d.keyStreamDecoder(d, s)
+		if err := s.option.limits.checkKeys(keys, s.totalOffset()); err != nil {
+			return err
+		}
+		var (
+			field *structFieldSet
+			key   string
+			err   error
+		)
+		if d.inlineField != nil {
+			field, key, err = d.decodeInlineKeyStream(s)
+		} else {
+			field, key, err = d.keyStreamDecoder(d, s)
+		}
 		if err != nil {
 			return err
 		}
@@ -533,10 +771,19 @@ func (d *structDecoder) decodeStream(s *stream, depth int64, p unsafe.Pointer) e
 			if field.err != nil {
 				return field.err
 			}
+			markDecodedField(decoded, field)
+			n, start := len(s.errs), s.totalOffset()
 			if err := field.dec.decodeStream(s, depth, unsafe.Pointer(uintptr(p)+field.offset)); err != nil {
+				if err := s.recoverTypeError(err, start, depth); err != nil {
+					return prependErrorPath(err, PathElement{Key: field.key, Index: -1})
+				}
+			}
+			s.prependCollectedPath(n, PathElement{Key: field.key, Index: -1})
+		} else if d.inlineField != nil {
+			if err := d.inlineField.decodeStream(s, depth, key, p); err != nil {
 				return err
 			}
-		} else if s.disallowUnknownFields {
+		} else if (s.option.Flag & DecodeOptionDisallowUnknownFields) != 0 {
 			return fmt.Errorf("json: unknown field %q", key)
 		} else {
 			if err := s.skipValue(depth); err != nil {
@@ -546,6 +793,9 @@ func (d *structDecoder) decodeStream(s *stream, depth int64, p unsafe.Pointer) e
 		s.skipWhiteSpace()
 		c := s.char()
 		if c == '}' {
+			if err := d.finishObject(decoded, s.totalOffset(), s.option, &s.errs, p); err != nil {
+				return err
+			}
 			s.cursor++
 			return nil
 		}
@@ -556,10 +806,11 @@ func (d *structDecoder) decodeStream(s *stream, depth int64, p unsafe.Pointer) e
 	}
 }
 
-func (d *structDecoder) decode(buf []byte, cursor, depth int64, p unsafe.Pointer) (int64, error) {
+func (d *structDecoder) decode(ctx *runtimeContext, cursor, depth int64, p unsafe.Pointer) (int64, error) {
+	buf := ctx.buf
 	depth++
-	if depth > maxDecodeNestingDepth {
-		return 0, errExceededMaxDepth(buf[cursor], cursor)
+	if err := ctx.option.limits.checkDepth(depth, buf[cursor], cursor); err != nil {
+		return 0, err
 	}
 	buflen := int64(len(buf))
 	cursor = skipWhiteSpace(buf, cursor)
@@ -586,15 +837,38 @@ func (d *structDecoder) decode(buf []byte, cursor, depth int64, p unsafe.Pointer
 	}
 	cursor++
 	cursor = skipWhiteSpace(buf, cursor)
+	var bitmap [1]uint64
+	decoded := d.decodedBitmap(bitmap[:])
 	if buf[cursor] == '}' {
+		if err := d.finishObject(decoded, cursor, ctx.option, &ctx.errs, p); err != nil {
+			return 0, err
+		}
 		cursor++
 		return cursor, nil
 	}
-	for {
-		c, field, err := d.keyDecoder(d, buf, cursor)
+	for keys := int64(1); ; keys++ {
+		if err := ctx.option.limits.checkKeys(keys, cursor); err != nil {
+			return 0, err
+		}
+		keyStart := cursor
+		var (
+			c         int64
+			field     *structFieldSet
+			inlineKey string
+			err       error
+		)
+		if d.inlineField != nil {
+			c, field, inlineKey, err = d.decodeInlineKey(ctx, cursor)
+		} else {
+			c, field, err = d.keyDecoder(d, ctx, cursor)
+		}
 		if err != nil {
 			return 0, err
 		}
+		if field == nil && d.inlineField == nil && (ctx.option.Flag&DecodeOptionDisallowUnknownFields) != 0 {
+			keyStart = skipWhiteSpace(buf, keyStart)
+			return 0, fmt.Errorf("json: unknown field %q", buf[keyStart+1:c-1])
+		}
 		cursor = skipWhiteSpace(buf, c)
 		if char(b, cursor) != ':' {
 			return 0, errExpected("colon after object key", cursor)
@@ -607,7 +881,18 @@ func (d *structDecoder) decode(buf []byte, cursor, depth int64, p unsafe.Pointer
 			if field.err != nil {
 				return 0, field.err
 			}
-			c, err := field.dec.decode(buf, cursor, depth, unsafe.Pointer(uintptr(p)+field.offset))
+			markDecodedField(decoded, field)
+			n := len(ctx.errs)
+			c, err := field.dec.decode(ctx, cursor, depth, unsafe.Pointer(uintptr(p)+field.offset))
+			if err != nil {
+				if c, err = ctx.recoverTypeError(err, cursor, depth); err != nil {
+					return 0, prependErrorPath(err, PathElement{Key: field.key, Index: -1})
+				}
+			}
+			ctx.prependCollectedPath(n, PathElement{Key: field.key, Index: -1})
+			cursor = c
+		} else if d.inlineField != nil {
+			c, err := d.inlineField.decode(ctx, cursor, depth, inlineKey, p)
 			if err != nil {
 				return 0, err
 			}
@@ -621,6 +906,9 @@ func (d *structDecoder) decode(buf []byte, cursor, depth int64, p unsafe.Pointer
 		}
 		cursor = skipWhiteSpace(buf, cursor)
 		if char(b, cursor) == '}' {
+			if err := d.finishObject(decoded, cursor, ctx.option, &ctx.errs, p); err != nil {
+				return 0, err
+			}
 			cursor++
 			return cursor, nil
 		}
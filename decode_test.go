@@ -2,10 +2,12 @@ package json_test
 
 import (
 	"bytes"
+	"context"
 	"encoding"
 	"errors"
 	"fmt"
 	"image"
+	"io"
 	"math"
 	"math/big"
 	"net"
@@ -740,28 +742,28 @@ type DoublePtr struct {
 
 var unmarshalTests = []unmarshalTest{
 	// basic types
-	{in: `true`, ptr: new(bool), out: true},                                                                                                                       // 0
-	{in: `1`, ptr: new(int), out: 1},                                                                                                                              // 1
-	{in: `1.2`, ptr: new(float64), out: 1.2},                                                                                                                      // 2
-	{in: `-5`, ptr: new(int16), out: int16(-5)},                                                                                                                   // 3
-	{in: `2`, ptr: new(json.Number), out: json.Number("2"), useNumber: true},                                                                                      // 4
-	{in: `2`, ptr: new(json.Number), out: json.Number("2")},                                                                                                       // 5
-	{in: `2`, ptr: new(interface{}), out: float64(2.0)},                                                                                                           // 6
-	{in: `2`, ptr: new(interface{}), out: json.Number("2"), useNumber: true},                                                                                      // 7
-	{in: `"a\u1234"`, ptr: new(string), out: "a\u1234"},                                                                                                           // 8
-	{in: `"http:\/\/"`, ptr: new(string), out: "http://"},                                                                                                         // 9
-	{in: `"g-clef: \uD834\uDD1E"`, ptr: new(string), out: "g-clef: \U0001D11E"},                                                                                   // 10
-	{in: `"invalid: \uD834x\uDD1E"`, ptr: new(string), out: "invalid: \uFFFDx\uFFFD"},                                                                             // 11
-	{in: "null", ptr: new(interface{}), out: nil},                                                                                                                 // 12
-	{in: `{"X": [1,2,3], "Y": 4}`, ptr: new(T), out: T{Y: 4}, err: &json.UnmarshalTypeError{"array", reflect.TypeOf(""), 7, "T", "X"}},                            // 13
-	{in: `{"X": 23}`, ptr: new(T), out: T{}, err: &json.UnmarshalTypeError{"number", reflect.TypeOf(""), 8, "T", "X"}}, {in: `{"x": 1}`, ptr: new(tx), out: tx{}}, // 14
+	{in: `true`, ptr: new(bool), out: true},                                           // 0
+	{in: `1`, ptr: new(int), out: 1},                                                  // 1
+	{in: `1.2`, ptr: new(float64), out: 1.2},                                          // 2
+	{in: `-5`, ptr: new(int16), out: int16(-5)},                                       // 3
+	{in: `2`, ptr: new(json.Number), out: json.Number("2"), useNumber: true},          // 4
+	{in: `2`, ptr: new(json.Number), out: json.Number("2")},                           // 5
+	{in: `2`, ptr: new(interface{}), out: float64(2.0)},                               // 6
+	{in: `2`, ptr: new(interface{}), out: json.Number("2"), useNumber: true},          // 7
+	{in: `"a\u1234"`, ptr: new(string), out: "a\u1234"},                               // 8
+	{in: `"http:\/\/"`, ptr: new(string), out: "http://"},                             // 9
+	{in: `"g-clef: \uD834\uDD1E"`, ptr: new(string), out: "g-clef: \U0001D11E"},       // 10
+	{in: `"invalid: \uD834x\uDD1E"`, ptr: new(string), out: "invalid: \uFFFDx\uFFFD"}, // 11
+	{in: "null", ptr: new(interface{}), out: nil},                                     // 12
+	{in: `{"X": [1,2,3], "Y": 4}`, ptr: new(T), out: T{Y: 4}, err: &json.UnmarshalTypeError{Value: "array", Type: reflect.TypeOf(""), Offset: 7, Struct: "T", Field: "X"}},                            // 13
+	{in: `{"X": 23}`, ptr: new(T), out: T{}, err: &json.UnmarshalTypeError{Value: "number", Type: reflect.TypeOf(""), Offset: 8, Struct: "T", Field: "X"}}, {in: `{"x": 1}`, ptr: new(tx), out: tx{}}, // 14
 	{in: `{"x": 1}`, ptr: new(tx), out: tx{}}, // 15, 16
-	{in: `{"x": 1}`, ptr: new(tx), err: fmt.Errorf("json: unknown field \"x\""), disallowUnknownFields: true},                           // 17
-	{in: `{"S": 23}`, ptr: new(W), out: W{}, err: &json.UnmarshalTypeError{"number", reflect.TypeOf(SS("")), 0, "W", "S"}},              // 18
-	{in: `{"F1":1,"F2":2,"F3":3}`, ptr: new(V), out: V{F1: float64(1), F2: int32(2), F3: json.Number("3")}},                             // 19
-	{in: `{"F1":1,"F2":2,"F3":3}`, ptr: new(V), out: V{F1: json.Number("1"), F2: int32(2), F3: json.Number("3")}, useNumber: true},      // 20
-	{in: `{"k1":1,"k2":"s","k3":[1,2.0,3e-3],"k4":{"kk1":"s","kk2":2}}`, ptr: new(interface{}), out: ifaceNumAsFloat64},                 // 21
-	{in: `{"k1":1,"k2":"s","k3":[1,2.0,3e-3],"k4":{"kk1":"s","kk2":2}}`, ptr: new(interface{}), out: ifaceNumAsNumber, useNumber: true}, // 22
+	{in: `{"x": 1}`, ptr: new(tx), err: fmt.Errorf("json: unknown field \"x\""), disallowUnknownFields: true},                                                  // 17
+	{in: `{"S": 23}`, ptr: new(W), out: W{}, err: &json.UnmarshalTypeError{Value: "number", Type: reflect.TypeOf(SS("")), Offset: 0, Struct: "W", Field: "S"}}, // 18
+	{in: `{"F1":1,"F2":2,"F3":3}`, ptr: new(V), out: V{F1: float64(1), F2: int32(2), F3: json.Number("3")}},                                                    // 19
+	{in: `{"F1":1,"F2":2,"F3":3}`, ptr: new(V), out: V{F1: json.Number("1"), F2: int32(2), F3: json.Number("3")}, useNumber: true},                             // 20
+	{in: `{"k1":1,"k2":"s","k3":[1,2.0,3e-3],"k4":{"kk1":"s","kk2":2}}`, ptr: new(interface{}), out: ifaceNumAsFloat64},                                        // 21
+	{in: `{"k1":1,"k2":"s","k3":[1,2.0,3e-3],"k4":{"kk1":"s","kk2":2}}`, ptr: new(interface{}), out: ifaceNumAsNumber, useNumber: true},                        // 22
 
 	// raw values with whitespace
 	{in: "\n true ", ptr: new(bool), out: true},                  // 23
@@ -2929,3 +2931,1352 @@ func TestDecodeSlice(t *testing.T) {
 		t.Fatal("invaid address")
 	}
 }
+
+type unmarshalContextKey struct{}
+
+type unmarshalerContextType struct {
+	v int
+}
+
+func (u *unmarshalerContextType) UnmarshalJSON(ctx context.Context, b []byte) error {
+	v := ctx.Value(unmarshalContextKey{})
+	s, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("failed to propagate parent context.Context")
+	}
+	if s != "hello" {
+		return fmt.Errorf("failed to propagate parent context.Context")
+	}
+	u.v = 1
+	return nil
+}
+
+func TestUnmarshalContext(t *testing.T) {
+	ctx := context.WithValue(context.Background(), unmarshalContextKey{}, "hello")
+	t.Run("UnmarshalerContext", func(t *testing.T) {
+		var v unmarshalerContextType
+		if err := json.UnmarshalContext(ctx, []byte(`{}`), &v); err != nil {
+			t.Fatal(err)
+		}
+		if v.v != 1 {
+			t.Fatal("failed to call UnmarshalJSON")
+		}
+	})
+	t.Run("struct field and slice element", func(t *testing.T) {
+		var v struct {
+			A unmarshalerContextType    `json:"a"`
+			B []*unmarshalerContextType `json:"b"`
+		}
+		if err := json.UnmarshalContext(ctx, []byte(`{"a":1,"b":[{"c":2}]}`), &v); err != nil {
+			t.Fatal(err)
+		}
+		if v.A.v != 1 || len(v.B) != 1 || v.B[0].v != 1 {
+			t.Fatal("failed to call UnmarshalJSON")
+		}
+	})
+	t.Run("without context", func(t *testing.T) {
+		var v unmarshalerContextType
+		if err := json.Unmarshal([]byte(`{}`), &v); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+	t.Run("Decoder.DecodeContext", func(t *testing.T) {
+		var v struct {
+			A unmarshalerContextType `json:"a"`
+		}
+		dec := json.NewDecoder(strings.NewReader(`{"a":"x"}`))
+		if err := dec.DecodeContext(ctx, &v); err != nil {
+			t.Fatal(err)
+		}
+		if v.A.v != 1 {
+			t.Fatal("failed to call UnmarshalJSON")
+		}
+	})
+}
+
+type cancelReader struct {
+	cancel func()
+	data   []string
+}
+
+func (r *cancelReader) Read(b []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(b, r.data[0])
+	r.data = r.data[1:]
+	r.cancel()
+	return n, nil
+}
+
+func TestDecodeContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := &cancelReader{cancel: cancel, data: []string{`{"a":`, `"b"}`}}
+	var v map[string]string
+	if err := json.NewDecoder(r).DecodeContext(ctx, &v); err != context.Canceled {
+		t.Fatalf("expected context.Canceled but got %v", err)
+	}
+}
+
+func TestUnmarshalWithOption(t *testing.T) {
+	t.Run("UseNumber", func(t *testing.T) {
+		var v interface{}
+		if err := json.UnmarshalWithOption([]byte(`[1.5]`), &v, json.UseNumber()); err != nil {
+			t.Fatal(err)
+		}
+		arr, ok := v.([]interface{})
+		if !ok || len(arr) != 1 {
+			t.Fatalf("unexpected value %v", v)
+		}
+		if n, ok := arr[0].(json.Number); !ok || n != "1.5" {
+			t.Fatalf("expected json.Number but got %T", arr[0])
+		}
+	})
+	t.Run("DisallowUnknownFields", func(t *testing.T) {
+		var v struct {
+			A int `json:"a"`
+		}
+		err := json.UnmarshalWithOption([]byte(`{"a":1, "b":2}`), &v, json.DisallowUnknownFields())
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if err.Error() != `json: unknown field "b"` {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := json.Unmarshal([]byte(`{"a":1, "b":2}`), &v); err != nil {
+			t.Fatal(err)
+		}
+	})
+	t.Run("custom option", func(t *testing.T) {
+		custom := func(opt *json.DecodeOption) {
+			opt.Flag = json.DecodeOptionUseNumber
+		}
+		var v []interface{}
+		err := json.UnmarshalWithOption([]byte(`[1,2]`), &v, json.DecodeWithLimits(json.DecodeLimits{MaxElements: 1}), custom)
+		var lerr *json.ElementsLimitError
+		if !errors.As(err, &lerr) {
+			t.Fatalf("expected ElementsLimitError but got %T: %v", err, err)
+		}
+		assertErr(t, json.UnmarshalWithOption([]byte(`[1]`), &v, custom))
+		assertEq(t, "number", json.Number("1"), v[0])
+	})
+	t.Run("Decoder.DecodeWithOption", func(t *testing.T) {
+		var v interface{}
+		dec := json.NewDecoder(strings.NewReader(`1 2`))
+		if err := dec.DecodeWithOption(&v, json.UseNumber()); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := v.(json.Number); !ok {
+			t.Fatalf("expected json.Number but got %T", v)
+		}
+		if err := dec.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := v.(float64); !ok {
+			t.Fatalf("expected float64 but got %T", v)
+		}
+	})
+}
+
+func TestUnmarshalWithFieldNaming(t *testing.T) {
+	type owner struct {
+		UserID   int
+		HTTPAddr string
+	}
+	type T struct {
+		FirstName string
+		Tagged    string `json:"TAGGED"`
+		Owner     *owner
+		Owners    []owner
+	}
+	src := []byte(`{"first_name":"foo","TAGGED":"bar","owner":{"user_id":1,"http_addr":"addr"},"owners":[{"user_id":2}]}`)
+	t.Run("Unmarshal", func(t *testing.T) {
+		var v T
+		assertErr(t, json.UnmarshalWithOption(src, &v, json.DecodeFieldNaming(json.SnakeCase)))
+		assertEq(t, "first_name", "foo", v.FirstName)
+		assertEq(t, "TAGGED", "bar", v.Tagged)
+		assertEq(t, "owner.user_id", 1, v.Owner.UserID)
+		assertEq(t, "owner.http_addr", "addr", v.Owner.HTTPAddr)
+		assertEq(t, "owners.user_id", 2, v.Owners[0].UserID)
+	})
+	t.Run("Decoder", func(t *testing.T) {
+		var v T
+		assertErr(t, json.NewDecoder(bytes.NewReader(src)).DecodeWithOption(&v, json.DecodeFieldNaming(json.SnakeCase)))
+		assertEq(t, "first_name", "foo", v.FirstName)
+		assertEq(t, "owner.user_id", 1, v.Owner.UserID)
+	})
+	t.Run("default naming is not changed", func(t *testing.T) {
+		var v T
+		assertErr(t, json.Unmarshal([]byte(`{"FirstName":"foo","first_name":"bar"}`), &v))
+		assertEq(t, "FirstName", "foo", v.FirstName)
+	})
+}
+
+type registeredRange struct {
+	Min, Max int
+}
+
+// UnmarshalJSON is overridden by the registered decoder
+func (r *registeredRange) UnmarshalJSON([]byte) error {
+	return errors.New("unexpected call")
+}
+
+func init() {
+	json.RegisterTypeDecoder(reflect.TypeOf(registeredRange{}), func(data []byte, v interface{}) error {
+		s, err := strconv.Unquote(string(data))
+		if err != nil {
+			return err
+		}
+		r := v.(*registeredRange)
+		if _, err := fmt.Sscanf(s, "%d..%d", &r.Min, &r.Max); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+func TestRegisterTypeDecoder(t *testing.T) {
+	type T struct {
+		Range    registeredRange
+		RangePtr *registeredRange
+		Ranges   []registeredRange
+		RangeMap map[string]registeredRange
+	}
+	src := []byte(`{"Range":"1..2","RangePtr":"3..4","Ranges":["5..6"],"RangeMap":{"a":"7..8"}}`)
+	assertRanges := func(t *testing.T, v T) {
+		t.Helper()
+		assertEq(t, "Range", "{1 2}", fmt.Sprint(v.Range))
+		assertEq(t, "RangePtr", "{3 4}", fmt.Sprint(*v.RangePtr))
+		assertEq(t, "Ranges", "[{5 6}]", fmt.Sprint(v.Ranges))
+		assertEq(t, "RangeMap", "{7 8}", fmt.Sprint(v.RangeMap["a"]))
+	}
+	t.Run("Unmarshal", func(t *testing.T) {
+		var v T
+		assertErr(t, json.Unmarshal(src, &v))
+		assertRanges(t, v)
+	})
+	t.Run("Decoder", func(t *testing.T) {
+		var v T
+		assertErr(t, json.NewDecoder(bytes.NewReader(src)).Decode(&v))
+		assertRanges(t, v)
+	})
+	t.Run("value", func(t *testing.T) {
+		var v registeredRange
+		assertErr(t, json.Unmarshal([]byte(`"9..10"`), &v))
+		assertEq(t, "range", "{9 10}", fmt.Sprint(v))
+	})
+	t.Run("error", func(t *testing.T) {
+		var v T
+		if err := json.Unmarshal([]byte(`{"Range":1}`), &v); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
+func TestUnmarshalCaseSensitiveKeys(t *testing.T) {
+	type T struct {
+		ID   string `json:"id"`
+		Name string
+	}
+	type marked struct {
+		json.CaseSensitiveKeys
+		ID string `json:"id"`
+	}
+	type many struct {
+		F1, F2, F3, F4, F5, F6, F7, F8, F9, F10 string
+	}
+	src := []byte(`{"ID":"upper","id":"lower","NAME":"upper","Name":"exact"}`)
+	t.Run("default folds case", func(t *testing.T) {
+		var v T
+		assertErr(t, json.Unmarshal([]byte(`{"id":"lower","ID":"upper"}`), &v))
+		assertEq(t, "id", "upper", v.ID)
+	})
+	t.Run("option", func(t *testing.T) {
+		var v T
+		assertErr(t, json.UnmarshalWithOption(src, &v, json.DecodeCaseSensitiveKeys()))
+		assertEq(t, "id", "lower", v.ID)
+		assertEq(t, "name", "exact", v.Name)
+	})
+	t.Run("option with Decoder", func(t *testing.T) {
+		var v T
+		assertErr(t, json.NewDecoder(bytes.NewReader(src)).DecodeWithOption(&v, json.DecodeCaseSensitiveKeys()))
+		assertEq(t, "id", "lower", v.ID)
+		assertEq(t, "name", "exact", v.Name)
+	})
+	t.Run("option with many fields", func(t *testing.T) {
+		var v many
+		assertErr(t, json.UnmarshalWithOption([]byte(`{"f1":"a","F10":"b","F1":"c"}`), &v, json.DecodeCaseSensitiveKeys()))
+		assertEq(t, "F1", "c", v.F1)
+		assertEq(t, "F10", "b", v.F10)
+	})
+	t.Run("unknown field", func(t *testing.T) {
+		var v T
+		err := json.UnmarshalWithOption([]byte(`{"ID":"upper"}`), &v, json.DecodeCaseSensitiveKeys(), json.DisallowUnknownFields())
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	})
+	t.Run("embedded CaseSensitiveKeys", func(t *testing.T) {
+		var v marked
+		assertErr(t, json.Unmarshal([]byte(`{"id":"lower","ID":"upper"}`), &v))
+		assertEq(t, "id", "lower", v.ID)
+		var vs []marked
+		assertErr(t, json.NewDecoder(strings.NewReader(`[{"id":"lower","Id":"mixed"}]`)).Decode(&vs))
+		assertEq(t, "id", "lower", vs[0].ID)
+		b, err := json.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "encoded", `{"id":"lower"}`, string(b))
+	})
+}
+
+func TestDecodeLimits(t *testing.T) {
+	type T struct {
+		Name  string
+		Tags  []string
+		Attrs map[string]int
+		Any   interface{}
+	}
+	tests := []struct {
+		name   string
+		src    string
+		limits json.DecodeLimits
+		err    interface{}
+	}{
+		{"depth", `{"Any":[[[1]]]}`, json.DecodeLimits{MaxDepth: 3}, new(*json.DepthLimitError)},
+		{"bytes", `{"Name":"foo"}`, json.DecodeLimits{MaxBytes: 10}, new(*json.BytesLimitError)},
+		{"string", `{"Name":"foobar"}`, json.DecodeLimits{MaxStringBytes: 5}, new(*json.StringLimitError)},
+		{"string in interface", `{"Any":"foobar"}`, json.DecodeLimits{MaxStringBytes: 5}, new(*json.StringLimitError)},
+		{"elements", `{"Tags":["a","b","c"]}`, json.DecodeLimits{MaxElements: 2}, new(*json.ElementsLimitError)},
+		{"elements in interface", `{"Any":[1,2,3]}`, json.DecodeLimits{MaxElements: 2}, new(*json.ElementsLimitError)},
+		{"map keys", `{"Attrs":{"a":1,"b":2,"c":3}}`, json.DecodeLimits{MaxKeys: 2}, new(*json.KeysLimitError)},
+		{"struct keys", `{"Name":"a","Tags":[],"Any":null}`, json.DecodeLimits{MaxKeys: 2}, new(*json.KeysLimitError)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var v T
+			err := json.UnmarshalWithOption([]byte(test.src), &v, json.DecodeWithLimits(test.limits))
+			if !errors.As(err, test.err) {
+				t.Fatalf("Unmarshal: expected %T but got %v", reflect.ValueOf(test.err).Elem().Interface(), err)
+			}
+			err = json.NewDecoder(strings.NewReader(test.src)).DecodeWithOption(&v, json.DecodeWithLimits(test.limits))
+			if !errors.As(err, test.err) {
+				t.Fatalf("Decoder: expected %T but got %v", reflect.ValueOf(test.err).Elem().Interface(), err)
+			}
+		})
+	}
+	t.Run("within limits", func(t *testing.T) {
+		src := `{"Name":"foo","Tags":["a","b"],"Attrs":{"a":1},"Any":[{"x":"y"}]}`
+		limits := json.DecodeLimits{MaxDepth: 3, MaxBytes: int64(len(src)), MaxStringBytes: 4, MaxElements: 2, MaxKeys: 4}
+		var v T
+		assertErr(t, json.UnmarshalWithOption([]byte(src), &v, json.DecodeWithLimits(limits)))
+		assertEq(t, "tags", "[a b]", fmt.Sprint(v.Tags))
+		var sv T
+		assertErr(t, json.NewDecoder(strings.NewReader(src)).DecodeWithOption(&sv, json.DecodeWithLimits(limits)))
+		assertEq(t, "tags", "[a b]", fmt.Sprint(sv.Tags))
+	})
+}
+
+var errPathErrorUnmarshaler = errors.New("custom error")
+
+type pathErrorUnmarshaler struct{}
+
+func (*pathErrorUnmarshaler) UnmarshalJSON([]byte) error {
+	return errPathErrorUnmarshaler
+}
+
+func TestUnmarshalErrorPath(t *testing.T) {
+	type item struct {
+		Price  int
+		Custom pathErrorUnmarshaler
+	}
+	type order struct {
+		Items []item `json:"items"`
+	}
+	type T struct {
+		Orders  []order           `json:"orders"`
+		Indexed map[string][2]int `json:"indexed"`
+	}
+	tests := []struct {
+		name     string
+		src      string
+		path     string
+		elements string
+	}{
+		{
+			name:     "type error",
+			src:      `{"orders":[{},{"items":[{"Price":1},{"Price":"x"}]}]}`,
+			path:     "orders[1].items[1].Price",
+			elements: "[{orders -1} { 1} {items -1} { 1} {Price -1}]",
+		},
+		{
+			name: "map and array",
+			src:  `{"indexed":{"a.b":[1,true]}}`,
+			path: `indexed["a.b"][1]`,
+		},
+		{
+			name: "syntax error",
+			src:  `{"orders":[{"items":[{"Price":nul}]}]}`,
+			path: "orders[0].items[0].Price",
+		},
+	}
+	pathOf := func(err error) (string, []json.PathElement) {
+		var (
+			terr *json.UnmarshalTypeError
+			serr *json.SyntaxError
+		)
+		switch {
+		case errors.As(err, &terr):
+			return terr.Path, terr.PathElements
+		case errors.As(err, &serr):
+			return serr.Path, serr.PathElements
+		}
+		t.Fatalf("unexpected error: %v", err)
+		return "", nil
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var v T
+			path, elems := pathOf(json.Unmarshal([]byte(test.src), &v))
+			assertEq(t, "path", test.path, path)
+			if test.elements != "" {
+				assertEq(t, "elements", test.elements, fmt.Sprint(elems))
+			}
+			var sv T
+			path, _ = pathOf(json.NewDecoder(strings.NewReader(test.src)).Decode(&sv))
+			assertEq(t, "stream path", test.path, path)
+		})
+	}
+	t.Run("deep nesting", func(t *testing.T) {
+		const depth = 9999
+		var v interface{}
+		_, elems := pathOf(json.Unmarshal([]byte(strings.Repeat("[", depth)+"x"), &v))
+		assertEq(t, "elements", depth, len(elems))
+	})
+	t.Run("unmarshaler error", func(t *testing.T) {
+		// the error of the unmarshaler is returned as is without the path
+		var v T
+		err := json.Unmarshal([]byte(`{"orders":[{"items":[{"Custom":{}}]}]}`), &v)
+		assertEq(t, "error", errPathErrorUnmarshaler, err)
+		var sv T
+		err = json.NewDecoder(strings.NewReader(`{"orders":[{"items":[{"Custom":{}}]}]}`)).Decode(&sv)
+		assertEq(t, "stream error", errPathErrorUnmarshaler, err)
+	})
+	t.Run("missing element", func(t *testing.T) {
+		var v []interface{}
+		path, _ := pathOf(json.Unmarshal([]byte(`[1,,2]`), &v))
+		assertEq(t, "path", "", path)
+		path, _ = pathOf(json.Unmarshal([]byte(`[1,[2,]]`), &[]interface{}{}))
+		assertEq(t, "nested path", "[1]", path)
+	})
+}
+
+func TestUnmarshalCollectErrors(t *testing.T) {
+	type item struct {
+		Name  string
+		Price int
+		Tags  []string
+	}
+	type T struct {
+		Items  []item         `json:"items"`
+		Limits map[string]int `json:"limits"`
+		Point  [2]int         `json:"point"`
+		Valid  bool           `json:"valid"`
+	}
+	src := `{"items":[{"Name":"a","Price":"x","Tags":["t",1]},{"Name":2,"Price":3}],"limits":{"a":1,"b":{}},"point":[1,"2"],"valid":true}`
+	check := func(t *testing.T, v T, err error) {
+		t.Helper()
+		var errs json.DecodeErrors
+		if !errors.As(err, &errs) {
+			t.Fatalf("expected DecodeErrors but got %T: %v", err, err)
+		}
+		paths := make([]string, 0, len(errs))
+		for _, e := range errs {
+			terr := e.(*json.UnmarshalTypeError)
+			paths = append(paths, terr.Path)
+			assertEq(t, "value at offset", true, terr.Offset > 0 && terr.Offset < int64(len(src)))
+		}
+		assertEq(t, "paths", "[items[0].Price items[0].Tags[1] items[1].Name limits.b point[1]]", fmt.Sprint(paths))
+		assertEq(t, "items", `[{a 0 [t ]} { 3 []}]`, fmt.Sprint(v.Items))
+		assertEq(t, "limits", 1, v.Limits["a"])
+		assertEq(t, "point", "[1 0]", fmt.Sprint(v.Point))
+		assertEq(t, "valid", true, v.Valid)
+	}
+	t.Run("Unmarshal", func(t *testing.T) {
+		var v T
+		err := json.UnmarshalWithOption([]byte(src), &v, json.CollectErrors())
+		check(t, v, err)
+	})
+	t.Run("Decoder", func(t *testing.T) {
+		var v T
+		dec := json.NewDecoder(strings.NewReader(src + `{"items":[{"Price":"no"}]}`))
+		err := dec.DecodeWithOption(&v, json.CollectErrors())
+		check(t, v, err)
+		var next T
+		err = dec.DecodeWithOption(&next, json.CollectErrors())
+		var errs json.DecodeErrors
+		if !errors.As(err, &errs) || len(errs) != 1 {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assertEq(t, "path", "items[0].Price", errs[0].(*json.UnmarshalTypeError).Path)
+		assertEq(t, "more", false, dec.More())
+	})
+	t.Run("top level", func(t *testing.T) {
+		var v int
+		err := json.UnmarshalWithOption([]byte(`"1"`), &v, json.CollectErrors())
+		var errs json.DecodeErrors
+		if !errors.As(err, &errs) || len(errs) != 1 {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+	t.Run("deep nesting", func(t *testing.T) {
+		type node struct {
+			A *node
+			V int
+		}
+		const depth = 1000
+		src := strings.Repeat(`{"V":"x","A":`, depth) + `null` + strings.Repeat("}", depth)
+		var v node
+		err := json.UnmarshalWithOption([]byte(src), &v, json.CollectErrors())
+		var errs json.DecodeErrors
+		if !errors.As(err, &errs) || len(errs) != depth {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		elems := errs[depth-1].(*json.UnmarshalTypeError).PathElements
+		assertEq(t, "elements", depth, len(elems))
+		assertEq(t, "last element", "V", elems[depth-1].Key)
+	})
+	t.Run("syntax error stops decoding", func(t *testing.T) {
+		var v T
+		err := json.UnmarshalWithOption([]byte(`{"items":[{"Price":"x"},{"Price":nul}]}`), &v, json.CollectErrors())
+		var serr *json.SyntaxError
+		if !errors.As(err, &serr) {
+			t.Fatalf("expected SyntaxError but got %T: %v", err, err)
+		}
+	})
+	t.Run("disabled", func(t *testing.T) {
+		var v T
+		err := json.Unmarshal([]byte(src), &v)
+		var terr *json.UnmarshalTypeError
+		if !errors.As(err, &terr) {
+			t.Fatalf("expected UnmarshalTypeError but got %T: %v", err, err)
+		}
+		assertEq(t, "path", "items[0].Price", terr.Path)
+	})
+}
+
+func TestUnmarshalErrorPosition(t *testing.T) {
+	type T struct {
+		Name  string `json:"name"`
+		Price int    `json:"price"`
+	}
+	tests := []struct {
+		name   string
+		src    string
+		line   int
+		column int
+		source string
+	}{
+		{
+			name:   "syntax error",
+			src:    "{\n  \"name\": \"a\",\n  \"price\": 1x\n}",
+			line:   3,
+			column: 13,
+			source: "  \"price\": 1x\n            ^",
+		},
+		{
+			name:   "type error",
+			src:    "{\n\t\"name\": \"a\",\n\t\"price\": \"1\"\n}",
+			line:   3,
+			column: 11,
+			source: "\t\"price\": \"1\"\n\t         ^",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var v T
+			err := json.Unmarshal([]byte(test.src), &v)
+			line, column := json.ErrorPosition([]byte(test.src), err)
+			assertEq(t, "line", test.line, line)
+			assertEq(t, "column", test.column, column)
+			assertEq(t, "source", test.source, json.ErrorSource([]byte(test.src), err))
+
+			// the preceding values are discarded from the buffer of the stream
+			src := strings.Repeat("{\"name\":\"a\"}\n", 100) + test.src
+			dec := json.NewDecoder(strings.NewReader(src))
+			for i := 0; i < 100; i++ {
+				if err := dec.Decode(&v); err != nil {
+					t.Fatal(err)
+				}
+			}
+			err = dec.Decode(&v)
+			line, column = dec.ErrorPosition(err)
+			assertEq(t, "stream line", test.line+100, line)
+			assertEq(t, "stream column", test.column, column)
+			assertEq(t, "stream source", test.source, dec.ErrorSource(err))
+		})
+	}
+	t.Run("collected errors", func(t *testing.T) {
+		type R struct {
+			Price int `json:"price,required"`
+		}
+		var v []R
+		src := "[\n{\"price\":\"1\"},\n{\"price\":\"2\"},\n{}\n]"
+		lines := func(err error, position func(error) (int, int)) string {
+			var errs json.DecodeErrors
+			if !errors.As(err, &errs) {
+				t.Fatalf("expected DecodeErrors but got %v", err)
+			}
+			lines := make([]int, 0, len(errs))
+			for _, e := range errs {
+				line, _ := position(e)
+				lines = append(lines, line)
+			}
+			return fmt.Sprint(lines)
+		}
+		err := json.UnmarshalWithOption([]byte(src), &v, json.CollectErrors())
+		assertEq(t, "lines", "[2 3 4]", lines(err, func(err error) (int, int) {
+			return json.ErrorPosition([]byte(src), err)
+		}))
+		dec := json.NewDecoder(strings.NewReader(src))
+		err = dec.DecodeWithOption(&v, json.CollectErrors())
+		assertEq(t, "stream lines", "[2 3 4]", lines(err, dec.ErrorPosition))
+	})
+	t.Run("long line", func(t *testing.T) {
+		var v []int
+		src := "[" + strings.Repeat("1,", 100) + "true]"
+		err := json.Unmarshal([]byte(src), &v)
+		source := json.ErrorSource([]byte(src), err)
+		lines := strings.Split(source, "\n")
+		assertEq(t, "lines", 2, len(lines))
+		assertEq(t, "width", 80, len(lines[0]))
+		assertEq(t, "caret", "t", string(lines[0][len(lines[1])-1]))
+	})
+}
+
+func TestUnmarshalInline(t *testing.T) {
+	type T struct {
+		ID    int                        `json:"id"`
+		Extra map[string]json.RawMessage `json:",inline"`
+		Name  string                     `json:"name"`
+	}
+	src := `{"id":1,"tags":["a", "b"],"name":"n","NAME":"m","meta":{"k":null},"":0}`
+	check := func(t *testing.T, v T) {
+		t.Helper()
+		assertEq(t, "id", 1, v.ID)
+		assertEq(t, "name", "m", v.Name)
+		assertEq(t, "extra", 3, len(v.Extra))
+		assertEq(t, "tags", `["a", "b"]`, string(v.Extra["tags"]))
+		assertEq(t, "meta", `{"k":null}`, string(v.Extra["meta"]))
+		assertEq(t, "empty key", `0`, string(v.Extra[""]))
+	}
+	t.Run("Unmarshal", func(t *testing.T) {
+		var v T
+		assertErr(t, json.Unmarshal([]byte(src), &v))
+		check(t, v)
+	})
+	t.Run("Decoder", func(t *testing.T) {
+		var v T
+		assertErr(t, json.NewDecoder(strings.NewReader(src)).Decode(&v))
+		check(t, v)
+	})
+	t.Run("DisallowUnknownFields", func(t *testing.T) {
+		var v T
+		assertErr(t, json.UnmarshalWithOption([]byte(src), &v, json.DisallowUnknownFields()))
+		check(t, v)
+	})
+	t.Run("interface", func(t *testing.T) {
+		var v struct {
+			A     int                    `json:"a"`
+			Extra map[string]interface{} `json:",unknown"`
+		}
+		assertErr(t, json.Unmarshal([]byte(`{"a":1,"b":[true],"c":"s"}`), &v))
+		assertEq(t, "a", 1, v.A)
+		assertEq(t, "extra", "map[b:[true] c:s]", fmt.Sprint(v.Extra))
+	})
+	t.Run("embedded", func(t *testing.T) {
+		type E struct {
+			T
+			Other string `json:"other"`
+		}
+		var v E
+		assertErr(t, json.Unmarshal([]byte(`{"id":1,"other":"o","x":2}`), &v))
+		assertEq(t, "other", "o", v.Other)
+		assertEq(t, "x", `2`, string(v.Extra["x"]))
+	})
+	t.Run("embedded pointer", func(t *testing.T) {
+		type E struct {
+			*T
+			Other string `json:"other"`
+		}
+		type F struct {
+			E
+		}
+		for _, src := range []string{`{"id":1,"other":"o","x":2}`, `{"x":2,"other":"o"}`} {
+			var v E
+			assertErr(t, json.Unmarshal([]byte(src), &v))
+			assertEq(t, "other", "o", v.Other)
+			assertEq(t, "x", `2`, string(v.Extra["x"]))
+			var w F
+			assertErr(t, json.NewDecoder(strings.NewReader(src)).Decode(&w))
+			assertEq(t, "other", "o", w.Other)
+			assertEq(t, "x", `2`, string(w.Extra["x"]))
+		}
+	})
+	t.Run("keys like the fields", func(t *testing.T) {
+		src := `{"i":1,"idx":2,"nam":3,"\u0069d":4,"na\"me":5,"Name":"n"}`
+		for _, decode := range []func(v interface{}) error{
+			func(v interface{}) error { return json.Unmarshal([]byte(src), v) },
+			func(v interface{}) error { return json.NewDecoder(strings.NewReader(src)).Decode(v) },
+		} {
+			var v struct {
+				ID    int                        `json:"id"`
+				Name  string                     `json:"name"`
+				Extra map[string]json.RawMessage `json:",inline"`
+			}
+			assertErr(t, decode(&v))
+			assertEq(t, "id", 4, v.ID)
+			assertEq(t, "name", "n", v.Name)
+			assertEq(t, "extra", `map[i:1 idx:2 na"me:5 nam:3]`, fmt.Sprint(v.Extra))
+		}
+	})
+	t.Run("type error", func(t *testing.T) {
+		var v struct {
+			Extra map[string]int `json:",inline"`
+		}
+		err := json.Unmarshal([]byte(`{"a":1,"b":"x"}`), &v)
+		var terr *json.UnmarshalTypeError
+		if !errors.As(err, &terr) {
+			t.Fatalf("expected UnmarshalTypeError but got %T: %v", err, err)
+		}
+		assertEq(t, "path", "b", terr.Path)
+	})
+	t.Run("not map", func(t *testing.T) {
+		var v struct {
+			Extra string `json:",inline"`
+		}
+		assertErr(t, json.Unmarshal([]byte(`{"Extra":"e","x":1}`), &v))
+		assertEq(t, "extra", "e", v.Extra)
+	})
+}
+
+func TestUnmarshalRequired(t *testing.T) {
+	type Item struct {
+		ID   int    `json:"id,required"`
+		Name string `json:"name,required"`
+		Note string `json:"note"`
+	}
+	type Order struct {
+		Items []Item `json:"items,required"`
+	}
+	tests := []struct {
+		name string
+		src  string
+		keys string
+		path string
+	}{
+		{name: "all", src: `{"items":[{"id":1,"name":"a"}]}`},
+		{name: "null value", src: `{"items":[{"id":null,"NAME":"a"}]}`},
+		{name: "empty object", src: `{}`, keys: "[items]"},
+		{name: "nested", src: `{"items":[{"id":1,"name":"a"},{"note":"n"}]}`, keys: "[id name]", path: "items[1]"},
+		{name: "one", src: `{"items":[{"name":"a","note":"n"}]}`, keys: "[id]", path: "items[0]"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			check := func(t *testing.T, err error) {
+				t.Helper()
+				if test.keys == "" {
+					assertErr(t, err)
+					return
+				}
+				var merr *json.MissingFieldError
+				if !errors.As(err, &merr) {
+					t.Fatalf("expected MissingFieldError but got %T: %v", err, err)
+				}
+				assertEq(t, "keys", test.keys, fmt.Sprint(merr.Keys))
+				assertEq(t, "path", test.path, merr.Path)
+				assertEq(t, "offset", byte('}'), test.src[merr.Offset])
+			}
+			var v Order
+			check(t, json.Unmarshal([]byte(test.src), &v))
+			var sv Order
+			check(t, json.NewDecoder(strings.NewReader(test.src)).Decode(&sv))
+		})
+	}
+	t.Run("message", func(t *testing.T) {
+		var v Item
+		err := json.Unmarshal([]byte(`{}`), &v)
+		assertEq(t, "error", `json: missing required fields "id", "name" of Go struct Item`, err.Error())
+	})
+	t.Run("embedded", func(t *testing.T) {
+		var v struct {
+			Item
+			Extra string `json:"extra,required"`
+		}
+		err := json.Unmarshal([]byte(`{"id":1,"extra":"e"}`), &v)
+		var merr *json.MissingFieldError
+		if !errors.As(err, &merr) {
+			t.Fatalf("expected MissingFieldError but got %T: %v", err, err)
+		}
+		assertEq(t, "keys", "[name]", fmt.Sprint(merr.Keys))
+	})
+	t.Run("null object", func(t *testing.T) {
+		var v *Item
+		assertErr(t, json.Unmarshal([]byte(`null`), &v))
+	})
+	t.Run("collected", func(t *testing.T) {
+		src := `{"items":[{"id":"1","name":"a"},{"note":"n"},{"id":3,"name":"c"}]}`
+		check := func(t *testing.T, v Order, err error) {
+			t.Helper()
+			var errs json.DecodeErrors
+			if !errors.As(err, &errs) || len(errs) != 2 {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assertEq(t, "type error", "items[0].id", errs[0].(*json.UnmarshalTypeError).Path)
+			merr, ok := errs[1].(*json.MissingFieldError)
+			if !ok {
+				t.Fatalf("expected MissingFieldError but got %T: %v", errs[1], errs[1])
+			}
+			assertEq(t, "keys", "[id name]", fmt.Sprint(merr.Keys))
+			assertEq(t, "path", "items[1]", merr.Path)
+			assertEq(t, "items", "[{0 a } {0  n} {3 c }]", fmt.Sprint(v.Items))
+		}
+		var v Order
+		err := json.UnmarshalWithOption([]byte(src), &v, json.CollectErrors())
+		check(t, v, err)
+		var sv Order
+		err = json.NewDecoder(strings.NewReader(src)).DecodeWithOption(&sv, json.CollectErrors())
+		check(t, sv, err)
+	})
+}
+
+type recursiveDefaultNode struct {
+	N     int                   `json:"n"`
+	Name  string                `json:"name"`
+	Child *recursiveDefaultNode `json:"child" default:"{\"name\":\"kid\"}"`
+}
+
+func TestUnmarshalDefault(t *testing.T) {
+	type Server struct {
+		Host    string            `json:"host" default:"localhost"`
+		Port    int               `json:"port,default=8080"`
+		Debug   bool              `json:"debug" default:"true"`
+		Ratio   float64           `json:"ratio" default:"0.5"`
+		Name    *string           `json:"name" default:"server, \"main\""`
+		Tags    []string          `json:"tags,default=[\"a\",\"b\"]"`
+		Labels  map[string]int    `json:"labels" default:"{\"x\":1}"`
+		Point   [2]int            `json:"point" default:"[1, 2]"`
+		Any     interface{}       `json:"any" default:"{\"k\":[1]}"`
+		Limits  map[string]string `json:"limits"`
+		Timeout int               `json:"timeout,required,default=10"`
+	}
+	check := func(t *testing.T, v Server) {
+		t.Helper()
+		assertEq(t, "host", "localhost", v.Host)
+		assertEq(t, "port", 8080, v.Port)
+		assertEq(t, "debug", true, v.Debug)
+		assertEq(t, "ratio", 0.5, v.Ratio)
+		assertEq(t, "name", `server, "main"`, *v.Name)
+		assertEq(t, "tags", "[a b]", fmt.Sprint(v.Tags))
+		assertEq(t, "labels", "map[x:1]", fmt.Sprint(v.Labels))
+		assertEq(t, "point", "[1 2]", fmt.Sprint(v.Point))
+		assertEq(t, "any", "map[k:[1]]", fmt.Sprint(v.Any))
+		assertEq(t, "limits", true, v.Limits == nil)
+	}
+	src := `{"timeout":1}`
+	t.Run("Unmarshal", func(t *testing.T) {
+		var v Server
+		assertErr(t, json.Unmarshal([]byte(src), &v))
+		check(t, v)
+		assertEq(t, "timeout", 1, v.Timeout)
+	})
+	t.Run("Decoder", func(t *testing.T) {
+		var v Server
+		assertErr(t, json.NewDecoder(strings.NewReader(src)).Decode(&v))
+		check(t, v)
+	})
+	t.Run("not shared", func(t *testing.T) {
+		var v1, v2 Server
+		assertErr(t, json.Unmarshal([]byte(src), &v1))
+		v1.Tags[0] = "changed"
+		*v1.Name = "changed"
+		v1.Labels["x"] = 2
+		v1.Any.(map[string]interface{})["k"].([]interface{})[0] = "changed"
+		assertErr(t, json.Unmarshal([]byte(src), &v2))
+		check(t, v2)
+	})
+	t.Run("recursive type", func(t *testing.T) {
+		type Tree struct {
+			Name string  `json:"name" default:"leaf"`
+			Kids []*Tree `json:"kids" default:"[{\"kids\":[]}]"`
+		}
+		var v Tree
+		assertErr(t, json.Unmarshal([]byte(`{}`), &v))
+		assertEq(t, "name", "leaf", v.Name)
+		assertEq(t, "kids", 1, len(v.Kids))
+		assertEq(t, "kid", "leaf", v.Kids[0].Name)
+		assertEq(t, "kids of kid", 0, len(v.Kids[0].Kids))
+	})
+	t.Run("default contains the field itself", func(t *testing.T) {
+		var v recursiveDefaultNode
+		err := json.Unmarshal([]byte(`{"n":1}`), &v)
+		if err == nil || !strings.Contains(err.Error(), "contains the field itself") {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		err = json.NewDecoder(strings.NewReader(`{"n":1}`)).Decode(&v)
+		if err == nil || !strings.Contains(err.Error(), "contains the field itself") {
+			t.Fatalf("unexpected error of Decoder: %v", err)
+		}
+	})
+	t.Run("present keys", func(t *testing.T) {
+		var v Server
+		assertErr(t, json.Unmarshal([]byte(`{"host":"example.com","port":0,"tags":null,"timeout":1}`), &v))
+		assertEq(t, "host", "example.com", v.Host)
+		assertEq(t, "port", 0, v.Port)
+		assertEq(t, "tags", true, v.Tags == nil)
+		assertEq(t, "debug", true, v.Debug)
+	})
+	t.Run("overwrite", func(t *testing.T) {
+		v := Server{Port: 1, Tags: []string{"x", "y", "z"}}
+		assertErr(t, json.Unmarshal([]byte(src), &v))
+		check(t, v)
+	})
+	t.Run("required is checked first", func(t *testing.T) {
+		var v Server
+		err := json.Unmarshal([]byte(`{}`), &v)
+		var merr *json.MissingFieldError
+		if !errors.As(err, &merr) {
+			t.Fatalf("expected MissingFieldError but got %T: %v", err, err)
+		}
+	})
+	t.Run("invalid default", func(t *testing.T) {
+		var v struct {
+			N int `json:"n" default:"x"`
+		}
+		err := json.Unmarshal([]byte(`{}`), &v)
+		if err == nil || !strings.Contains(err.Error(), "invalid default value") {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var w struct {
+			N int `json:"n" default:"1 2"`
+		}
+		if err := json.Unmarshal([]byte(`{}`), &w); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+	t.Run("embedded", func(t *testing.T) {
+		type Base struct {
+			Level int `json:"level" default:"3"`
+		}
+		type Optional struct {
+			Retry int `json:"retry" default:"5"`
+		}
+		var v struct {
+			Base
+			*Optional
+		}
+		assertErr(t, json.Unmarshal([]byte(`{}`), &v))
+		assertEq(t, "level", 3, v.Level)
+		assertEq(t, "pointer is not allocated", true, v.Optional == nil)
+	})
+}
+
+func TestUnmarshalTagKey(t *testing.T) {
+	type inner struct {
+		Code string `json:"code" api:"c"`
+	}
+	type T struct {
+		ID       int     `json:"id" api:"identifier,required"`
+		Internal string  `json:"internal" api:"-"`
+		Items    []inner `json:"items" api:"list"`
+	}
+	src := `{"identifier":1,"internal":"x","id":2,"list":[{"c":"a","code":"b"}]}`
+	check := func(t *testing.T, v T) {
+		t.Helper()
+		assertEq(t, "id", 1, v.ID)
+		assertEq(t, "internal", "", v.Internal)
+		assertEq(t, "items", "[{a}]", fmt.Sprint(v.Items))
+	}
+	t.Run("Unmarshal", func(t *testing.T) {
+		var v T
+		assertErr(t, json.UnmarshalWithOption([]byte(src), &v, json.DecodeTagKey("api")))
+		check(t, v)
+	})
+	t.Run("Decoder", func(t *testing.T) {
+		var v T
+		assertErr(t, json.NewDecoder(strings.NewReader(src)).DecodeWithOption(&v, json.DecodeTagKey("api")))
+		check(t, v)
+	})
+	t.Run("json", func(t *testing.T) {
+		var v T
+		assertErr(t, json.Unmarshal([]byte(src), &v))
+		assertEq(t, "id", 2, v.ID)
+		assertEq(t, "internal", "x", v.Internal)
+		assertEq(t, "items", 0, len(v.Items))
+	})
+	t.Run("required by the key", func(t *testing.T) {
+		var v T
+		err := json.UnmarshalWithOption([]byte(`{"id":1}`), &v, json.DecodeTagKey("api"))
+		var merr *json.MissingFieldError
+		if !errors.As(err, &merr) {
+			t.Fatalf("expected MissingFieldError but got %T: %v", err, err)
+		}
+		assertEq(t, "keys", "[identifier]", fmt.Sprint(merr.Keys))
+	})
+}
+
+func TestUnmarshalMapSlice(t *testing.T) {
+	src := `{"b":1,"a":[true,null,"x"],"c":2.5}`
+	check := func(t *testing.T, v json.MapSlice) {
+		t.Helper()
+		assertEq(t, "items", "[{b 1} {a [true <nil> x]} {c 2.5}]", fmt.Sprint(v))
+	}
+	t.Run("Unmarshal", func(t *testing.T) {
+		var v json.MapSlice
+		assertErr(t, json.Unmarshal([]byte(src), &v))
+		check(t, v)
+		b, err := json.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "roundtrip", src, string(b))
+	})
+	t.Run("Decoder", func(t *testing.T) {
+		var v json.MapSlice
+		assertErr(t, json.NewDecoder(strings.NewReader(src)).Decode(&v))
+		check(t, v)
+	})
+	t.Run("nested objects keep the order", func(t *testing.T) {
+		src := `{"a":{"z":1,"y":[{"q":2,"p":3}]}}`
+		var v json.MapSlice
+		assertErr(t, json.Unmarshal([]byte(src), &v))
+		assertEq(t, "items", "[{a [{z 1} {y [[{q 2} {p 3}]]}]}]", fmt.Sprint(v))
+		b, err := json.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "roundtrip", src, string(b))
+		v = nil
+		assertErr(t, json.NewDecoder(strings.NewReader(src)).Decode(&v))
+		assertEq(t, "Decoder", "[{a [{z 1} {y [[{q 2} {p 3}]]}]}]", fmt.Sprint(v))
+	})
+	t.Run("empty object with spaces", func(t *testing.T) {
+		for _, src := range []string{`{ }`, `{"a":{ }}`, "{ \n\"a\" : 1 , \"b\":2 }"} {
+			var v, w json.MapSlice
+			assertErr(t, json.Unmarshal([]byte(src), &v))
+			assertErr(t, json.NewDecoder(strings.NewReader(src)).Decode(&w))
+			assertEq(t, "Decoder", fmt.Sprint(v), fmt.Sprint(w))
+		}
+	})
+	t.Run("replaces items", func(t *testing.T) {
+		v := json.MapSlice{{Key: "old", Value: 1}}
+		assertErr(t, json.Unmarshal([]byte(`{"new":2,"new":3}`), &v))
+		assertEq(t, "items", "[{new 2} {new 3}]", fmt.Sprint(v))
+		assertErr(t, json.Unmarshal([]byte(`null`), &v))
+		assertEq(t, "null", true, v == nil)
+	})
+	t.Run("struct field", func(t *testing.T) {
+		var v struct {
+			A json.MapSlice
+			B *json.MapSlice
+		}
+		assertErr(t, json.Unmarshal([]byte(`{"A":{"y":1,"x":2},"B":{"q":"r"}}`), &v))
+		assertEq(t, "A", "[{y 1} {x 2}]", fmt.Sprint(v.A))
+		assertEq(t, "B", "[{q r}]", fmt.Sprint(*v.B))
+	})
+	t.Run("type error", func(t *testing.T) {
+		var v json.MapSlice
+		err := json.Unmarshal([]byte(`[1]`), &v)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
+func TestUnmarshalUseMapSlice(t *testing.T) {
+	src := `{"b":{"y":1,"x":[{"q":null,"p":true}]},"a":[{"d":"e","c":{}}],"c":"s"}`
+	expected := "[{b [{y 1} {x [[{q <nil>} {p true}]]}]} {a [[{d e} {c []}]]} {c s}]"
+	t.Run("Unmarshal", func(t *testing.T) {
+		var v interface{}
+		assertErr(t, json.UnmarshalWithOption([]byte(src), &v, json.UseMapSlice()))
+		if _, ok := v.(json.MapSlice); !ok {
+			t.Fatalf("unexpected type %T", v)
+		}
+		assertEq(t, "value", expected, fmt.Sprint(v))
+		b, err := json.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "roundtrip", src, string(b))
+	})
+	t.Run("Decoder", func(t *testing.T) {
+		var v interface{}
+		dec := json.NewDecoder(strings.NewReader(src))
+		dec.UseMapSlice()
+		assertErr(t, dec.Decode(&v))
+		assertEq(t, "value", expected, fmt.Sprint(v))
+		v = nil
+		assertErr(t, json.NewDecoder(strings.NewReader(src)).DecodeWithOption(&v, json.UseMapSlice()))
+		assertEq(t, "option", expected, fmt.Sprint(v))
+	})
+	t.Run("interface in struct and slice", func(t *testing.T) {
+		var v struct {
+			A interface{}
+			B []interface{}
+		}
+		assertErr(t, json.UnmarshalWithOption([]byte(`{"A":{"z":1,"y":2},"B":[{"x":3,"w":4}]}`), &v, json.UseMapSlice()))
+		assertEq(t, "A", "[{z 1} {y 2}]", fmt.Sprint(v.A))
+		assertEq(t, "B", "[[{x 3} {w 4}]]", fmt.Sprint(v.B))
+	})
+	t.Run("without option", func(t *testing.T) {
+		var v interface{}
+		assertErr(t, json.Unmarshal([]byte(`{"a":{"b":1}}`), &v))
+		if _, ok := v.(map[string]interface{})["a"].(map[string]interface{}); !ok {
+			t.Fatalf("unexpected type %T", v)
+		}
+	})
+}
+
+func TestUnmarshalUseInt64(t *testing.T) {
+	src := `[1,-2,9007199254740993,18446744073709551615,18446744073709551616,1.5,1e3,-0]`
+	check := func(t *testing.T, v interface{}, last string) {
+		t.Helper()
+		var types []string
+		for _, e := range v.([]interface{}) {
+			types = append(types, fmt.Sprintf("%T(%v)", e, e))
+		}
+		assertEq(t, "values", "[int64(1) int64(-2) int64(9007199254740993) uint64(18446744073709551615) "+last+"]", fmt.Sprint(types))
+	}
+	t.Run("Unmarshal", func(t *testing.T) {
+		var v interface{}
+		assertErr(t, json.UnmarshalWithOption([]byte(src), &v, json.UseInt64()))
+		check(t, v, "float64(1.8446744073709552e+19) float64(1.5) float64(1000) int64(0)")
+	})
+	t.Run("Decoder", func(t *testing.T) {
+		var v interface{}
+		dec := json.NewDecoder(strings.NewReader(src))
+		dec.UseInt64()
+		assertErr(t, dec.Decode(&v))
+		check(t, v, "float64(1.8446744073709552e+19) float64(1.5) float64(1000) int64(0)")
+	})
+	t.Run("with UseNumber", func(t *testing.T) {
+		var v interface{}
+		assertErr(t, json.UnmarshalWithOption([]byte(src), &v, json.UseInt64(), json.UseNumber()))
+		check(t, v, "json.Number(18446744073709551616) json.Number(1.5) json.Number(1e3) int64(0)")
+		v = nil
+		assertErr(t, json.NewDecoder(strings.NewReader(src)).DecodeWithOption(&v, json.UseInt64(), json.UseNumber()))
+		check(t, v, "json.Number(18446744073709551616) json.Number(1.5) json.Number(1e3) int64(0)")
+	})
+	t.Run("map value", func(t *testing.T) {
+		var v map[string]interface{}
+		assertErr(t, json.UnmarshalWithOption([]byte(`{"id":9007199254740993}`), &v, json.UseInt64()))
+		assertEq(t, "id", int64(9007199254740993), v["id"])
+	})
+	t.Run("invalid", func(t *testing.T) {
+		var v interface{}
+		if err := json.UnmarshalWithOption([]byte(`[1-2]`), &v, json.UseInt64()); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
+type unionShape interface {
+	Area() float64
+}
+
+type unionCircle struct {
+	Type   string  `json:"type"`
+	Radius float64 `json:"radius"`
+}
+
+func (c unionCircle) Area() float64 { return 3 * c.Radius * c.Radius }
+
+type unionRect struct {
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+func (r *unionRect) Area() float64 { return r.Width * r.Height }
+
+type unionGroup struct {
+	unionCircle
+	Inner *unionGroup `json:"inner,omitempty"`
+}
+
+func init() {
+	json.RegisterUnion((*unionShape)(nil), "type", map[string]unionShape{
+		"circle": unionCircle{},
+		"rect":   &unionRect{},
+		"group":  unionGroup{},
+	})
+}
+
+func TestUnmarshalUnion(t *testing.T) {
+	src := `[{"type":"circle","radius":2},{"width":2,"height":3,"type":"rect"},null]`
+	check := func(t *testing.T, v []unionShape) {
+		t.Helper()
+		var types []string
+		for _, e := range v {
+			types = append(types, fmt.Sprintf("%T%v", e, e))
+		}
+		assertEq(t, "shapes", "[json_test.unionCircle{circle 2} *json_test.unionRect&{2 3} <nil><nil>]", fmt.Sprint(types))
+	}
+	t.Run("Unmarshal", func(t *testing.T) {
+		var v []unionShape
+		assertErr(t, json.Unmarshal([]byte(src), &v))
+		check(t, v)
+	})
+	t.Run("Decoder", func(t *testing.T) {
+		var v []unionShape
+		assertErr(t, json.NewDecoder(strings.NewReader(src)).Decode(&v))
+		check(t, v)
+	})
+	t.Run("struct field", func(t *testing.T) {
+		var v struct {
+			Shape unionShape `json:"shape"`
+		}
+		assertErr(t, json.Unmarshal([]byte(`{"shape":{"type":"rect","width":4,"height":5}}`), &v))
+		assertEq(t, "area", float64(20), v.Shape.Area())
+	})
+	t.Run("escaped key", func(t *testing.T) {
+		var v unionShape
+		assertErr(t, json.Unmarshal([]byte(`{"radius":1,"ty\u0070e":"circ\u006ce"}`), &v))
+		assertEq(t, "area", float64(3), v.Area())
+	})
+	t.Run("errors", func(t *testing.T) {
+		for _, src := range []string{
+			`{"radius":1}`,
+			`{"type":"triangle"}`,
+			`{"type":1}`,
+			`[]`,
+		} {
+			var v unionShape
+			if err := json.Unmarshal([]byte(src), &v); err == nil {
+				t.Fatalf("expected error for %s", src)
+			}
+			if err := json.NewDecoder(strings.NewReader(src)).Decode(&v); err == nil {
+				t.Fatalf("expected error of Decoder for %s", src)
+			}
+		}
+		var v struct {
+			Shape unionShape `json:"shape"`
+		}
+		err := json.Unmarshal([]byte(`{"shape":{"type":"triangle"}}`), &v)
+		typeErr, ok := err.(*json.UnmarshalTypeError)
+		if !ok {
+			t.Fatalf("expected UnmarshalTypeError but got %v", err)
+		}
+		assertEq(t, "field", "Shape", typeErr.Field)
+	})
+	t.Run("error offset", func(t *testing.T) {
+		src := `{"type":"circle"} {"radius":1,"type":2}`
+		dec := json.NewDecoder(strings.NewReader(src))
+		var v unionShape
+		assertErr(t, dec.Decode(&v))
+		err := dec.Decode(&v)
+		typeErr, ok := err.(*json.UnmarshalTypeError)
+		if !ok {
+			t.Fatalf("expected UnmarshalTypeError but got %v", err)
+		}
+		assertEq(t, "offset", int64(strings.LastIndex(src, "2")), typeErr.Offset)
+
+		err = json.Unmarshal([]byte(`{"radius":1,"type":"\x"}`), &v)
+		syntaxErr, ok := err.(*json.SyntaxError)
+		if !ok {
+			t.Fatalf("expected SyntaxError but got %v", err)
+		}
+		assertEq(t, "offset", int64(21), syntaxErr.Offset)
+	})
+}
+
+func TestUnmarshalBorrow(t *testing.T) {
+	type T struct {
+		Name    string            `json:"name"`
+		Escaped string            `json:"escaped"`
+		Count   int               `json:"count,string"`
+		Raw     json.RawMessage   `json:"raw"`
+		Tags    map[string]string `json:"tags"`
+	}
+	const src = `{"name":"alice","escaped":"a\"bé","count":"3","raw":{"x":[1,2]},"tags":{"k":"v"}}`
+	pointsInto := func(v interface{}, buf []byte) bool {
+		var p uintptr
+		switch v := v.(type) {
+		case string:
+			p = (*reflect.StringHeader)(unsafe.Pointer(&v)).Data
+		case json.RawMessage:
+			p = uintptr(unsafe.Pointer(&v[0]))
+		}
+		start := uintptr(unsafe.Pointer(&buf[0]))
+		return start <= p && p < start+uintptr(len(buf))
+	}
+	check := func(t *testing.T, v T) {
+		t.Helper()
+		assertEq(t, "name", "alice", v.Name)
+		assertEq(t, "escaped", "a\"bé", v.Escaped)
+		assertEq(t, "count", 3, v.Count)
+		assertEq(t, "raw", `{"x":[1,2]}`, string(v.Raw))
+		assertEq(t, "tags", "v", v.Tags["k"])
+	}
+	t.Run("borrowed", func(t *testing.T) {
+		buf := append([]byte(src), 0)
+		data := buf[:len(buf)-1]
+		var v T
+		assertErr(t, json.UnmarshalBorrow(data, &v))
+		check(t, v)
+		assertEq(t, "unmodified", src, string(data))
+		assertEq(t, "name points into data", true, pointsInto(v.Name, data))
+		assertEq(t, "escaped is copied", false, pointsInto(v.Escaped, data))
+		assertEq(t, "raw points into data", true, pointsInto(v.Raw, data))
+		assertEq(t, "raw capacity", len(v.Raw), cap(v.Raw))
+	})
+	t.Run("copied", func(t *testing.T) {
+		data := []byte(src)
+		var v T
+		assertErr(t, json.UnmarshalBorrow(data[:len(data):len(data)], &v))
+		check(t, v)
+		assertEq(t, "unmodified", src, string(data))
+		assertEq(t, "name is copied", false, pointsInto(v.Name, data))
+	})
+	t.Run("interface", func(t *testing.T) {
+		buf := append([]byte(`["a","b\nc"]`), 0)
+		data := buf[:len(buf)-1]
+		var v interface{}
+		assertErr(t, json.UnmarshalBorrow(data, &v))
+		assertEq(t, "values", "[a b\nc]", fmt.Sprint(v))
+		assertEq(t, "unmodified", `["a","b\nc"]`, string(data))
+	})
+	t.Run("escaped keys", func(t *testing.T) {
+		// the keys of these structs are decoded without the bitmap of the keys
+		type conflicted struct {
+			Lower string `json:"ab"`
+			Upper string `json:"AB"`
+		}
+		type inline struct {
+			Name  string         `json:"name"`
+			Extra map[string]int `json:",inline"`
+		}
+		const src = `{"\u0061b":"l","A\u0042":"u"}`
+		const inlineSrc = `{"na\u006de":"n","k\"ey":1}`
+		for _, borrow := range []bool{false, true} {
+			unmarshal := json.UnmarshalWithOption
+			if borrow {
+				unmarshal = json.UnmarshalBorrow
+			}
+			buf := append([]byte(src), 0)
+			data := buf[:len(buf)-1]
+			var c conflicted
+			assertErr(t, unmarshal(data, &c))
+			assertEq(t, "conflicted", "l u", c.Lower+" "+c.Upper)
+			buf = append([]byte(inlineSrc), 0)
+			inlineData := buf[:len(buf)-1]
+			var i inline
+			assertErr(t, unmarshal(inlineData, &i))
+			assertEq(t, "inline", "n map[k\"ey:1]", i.Name+" "+fmt.Sprint(i.Extra))
+			if borrow {
+				assertEq(t, "unmodified", src, string(data))
+				assertEq(t, "unmodified inline", inlineSrc, string(inlineData))
+			}
+		}
+	})
+	t.Run("error", func(t *testing.T) {
+		buf := append([]byte(`{"name":"a\x"}`), 0)
+		var v T
+		if err := json.UnmarshalBorrow(buf[:len(buf)-1], &v); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
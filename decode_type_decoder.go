@@ -0,0 +1,93 @@
+package json
+
+import (
+	"reflect"
+	"sync"
+	"unsafe"
+)
+
+var (
+	typeDecodersMu sync.RWMutex
+	typeDecoders   = map[uintptr]func([]byte, interface{}) error{}
+)
+
+// RegisterTypeDecoder registers fn as the decoder of typ.
+// fn receives the JSON-encoded value and a pointer to the value of typ to store the result in.
+// It is checked before the Unmarshaler and encoding.TextUnmarshaler interfaces,
+// and works wherever typ appears ( e.g. struct fields, slice elements and map values ).
+//
+// data references the buffer of the decoder, so fn must not retain it after returning.
+// The compiled decoder of a type is cached, so RegisterTypeDecoder must be called before typ is decoded ( e.g. in init ).
+func RegisterTypeDecoder(typ reflect.Type, fn func(data []byte, v interface{}) error) {
+	typeDecodersMu.Lock()
+	defer typeDecodersMu.Unlock()
+	typeDecoders[uintptr(unsafe.Pointer(type2rtype(typ)))] = fn
+}
+
+func lookupTypeDecoder(typ *rtype) func([]byte, interface{}) error {
+	typeDecodersMu.RLock()
+	defer typeDecodersMu.RUnlock()
+	return typeDecoders[uintptr(unsafe.Pointer(typ))]
+}
+
+type typeDecoder struct {
+	typ        *rtype
+	fn         func([]byte, interface{}) error
+	structName string
+	fieldName  string
+}
+
+func newTypeDecoder(typ *rtype, fn func([]byte, interface{}) error, structName, fieldName string) *typeDecoder {
+	return &typeDecoder{
+		typ:        typ,
+		fn:         fn,
+		structName: structName,
+		fieldName:  fieldName,
+	}
+}
+
+func (d *typeDecoder) annotateError(cursor int64, err error) {
+	switch e := err.(type) {
+	case *UnmarshalTypeError:
+		e.Struct = d.structName
+		e.Field = d.fieldName
+	case *SyntaxError:
+		e.Offset = cursor
+	}
+}
+
+func (d *typeDecoder) decodeStream(s *stream, depth int64, p unsafe.Pointer) error {
+	s.skipWhiteSpace()
+	start := s.cursor
+	if err := s.skipValue(depth); err != nil {
+		return err
+	}
+	v := *(*interface{})(unsafe.Pointer(&emptyInterface{
+		typ: d.typ,
+		ptr: p,
+	}))
+	if err := d.fn(s.buf[start:s.cursor], v); err != nil {
+		d.annotateError(s.cursor, err)
+		return err
+	}
+	return nil
+}
+
+func (d *typeDecoder) decode(ctx *runtimeContext, cursor, depth int64, p unsafe.Pointer) (int64, error) {
+	buf := ctx.buf
+	cursor = skipWhiteSpace(buf, cursor)
+	start := cursor
+	end, err := skipValue(buf, cursor, depth)
+	if err != nil {
+		return 0, err
+	}
+	v := *(*interface{})(unsafe.Pointer(&emptyInterface{
+		typ: d.typ,
+		ptr: p,
+	}))
+	if err := d.fn(buf[start:end], v); err != nil {
+		d.annotateError(cursor, err)
+		return 0, err
+	}
+	return end, nil
+}
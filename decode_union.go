@@ -0,0 +1,178 @@
+package json
+
+import (
+	"fmt"
+	"reflect"
+	"unsafe"
+
+	"github.com/goccy/go-json/internal/runtime"
+)
+
+// unionDecoder decodes an object into an interface registered by RegisterUnion.
+// The concrete type is chosen by the value of the key of the union, and the object is decoded into a new value of it.
+type unionDecoder struct {
+	typ        *rtype
+	union      *runtime.Union
+	keyDecoder *stringDecoder
+	structName string
+	fieldName  string
+}
+
+func newUnionDecoder(typ *rtype, union *runtime.Union, structName, fieldName string) *unionDecoder {
+	return &unionDecoder{
+		typ:        typ,
+		union:      union,
+		keyDecoder: newStringDecoder(structName, fieldName),
+		structName: structName,
+		fieldName:  fieldName,
+	}
+}
+
+// concreteType returns the concrete type for the object that starts at cursor.
+// The object must be valid. base is the offset of buf in the input, and it is added to the offsets of errors.
+func (d *unionDecoder) concreteType(buf []byte, cursor, depth, base int64) (*rtype, error) {
+	name, found, err := d.findName(buf, cursor, depth)
+	if err != nil {
+		return nil, addErrorOffset(err, base)
+	}
+	if !found {
+		return nil, d.errUnmarshalType(fmt.Sprintf("object without %q key", d.union.Key), base+cursor)
+	}
+	typ, exists := d.union.Types[name]
+	if !exists {
+		return nil, d.errUnmarshalType(fmt.Sprintf("object with unknown %q %q", d.union.Key, name), base+cursor)
+	}
+	return typ, nil
+}
+
+// findName returns the string value of the key of the union in the object that starts at cursor.
+// The offsets of the errors are relative to buf.
+func (d *unionDecoder) findName(buf []byte, cursor, depth int64) (string, bool, error) {
+	cursor++
+	for {
+		cursor = skipWhiteSpace(buf, cursor)
+		if buf[cursor] != '"' {
+			return "", false, nil
+		}
+		key, keyEnd, err := d.keyDecoder.decodeByteCopy(buf, cursor)
+		if err != nil {
+			return "", false, err
+		}
+		cursor = skipWhiteSpace(buf, keyEnd) + 1 // colon after object key
+		cursor = skipWhiteSpace(buf, cursor)
+		if string(key) == d.union.Key {
+			if buf[cursor] != '"' {
+				return "", false, d.errUnmarshalType(fmt.Sprintf("object with non-string %q", d.union.Key), cursor)
+			}
+			name, _, err := d.keyDecoder.decodeByteCopy(buf, cursor)
+			if err != nil {
+				return "", false, err
+			}
+			return string(name), true, nil
+		}
+		cursor, err = skipValue(buf, cursor, depth)
+		if err != nil {
+			return "", false, err
+		}
+		cursor = skipWhiteSpace(buf, cursor)
+		if buf[cursor] != ',' {
+			return "", false, nil
+		}
+		cursor++
+	}
+}
+
+func (d *unionDecoder) errUnmarshalType(value string, offset int64) *UnmarshalTypeError {
+	return &UnmarshalTypeError{
+		Value:  value,
+		Type:   rtype2type(d.typ),
+		Offset: offset,
+		Struct: d.structName,
+		Field:  d.fieldName,
+	}
+}
+
+// set stores the value of typ at v into the interface at p.
+func (d *unionDecoder) set(typ *rtype, v unsafe.Pointer, p unsafe.Pointer) {
+	reflect.NewAt(rtype2type(d.typ), p).Elem().Set(reflect.NewAt(rtype2type(typ), v).Elem())
+}
+
+func (d *unionDecoder) decodeStream(s *stream, depth int64, p unsafe.Pointer) error {
+	s.skipWhiteSpace()
+	switch s.char() {
+	case 'n':
+		if err := nullBytes(s); err != nil {
+			return err
+		}
+		*(*interface{})(p) = nil
+		return nil
+	case '{':
+	default:
+		return errExpected("{ character for union value", s.totalOffset())
+	}
+	start := s.cursor
+	if err := s.skipValue(depth); err != nil {
+		return err
+	}
+	typ, err := d.concreteType(s.buf, start, depth, s.offset)
+	if err != nil {
+		return err
+	}
+	dec, err := decodeCompileToGetDecoderWithConfig(rtype_ptrTo(typ), s.option.config)
+	if err != nil {
+		return err
+	}
+	// decode the object again from the start, because the stream keeps the buffer of the current value
+	s.cursor = start
+	v := unsafe_New(typ)
+	if err := dec.decodeStream(s, depth, v); err != nil {
+		return err
+	}
+	d.set(typ, v, p)
+	return nil
+}
+
+func (d *unionDecoder) decode(ctx *runtimeContext, cursor, depth int64, p unsafe.Pointer) (int64, error) {
+	buf := ctx.buf
+	cursor = skipWhiteSpace(buf, cursor)
+	switch buf[cursor] {
+	case 'n':
+		if cursor+3 >= int64(len(buf)) {
+			return 0, errUnexpectedEndOfJSON("null", cursor)
+		}
+		if buf[cursor+1] != 'u' {
+			return 0, errInvalidCharacter(buf[cursor+1], "null", cursor)
+		}
+		if buf[cursor+2] != 'l' {
+			return 0, errInvalidCharacter(buf[cursor+2], "null", cursor)
+		}
+		if buf[cursor+3] != 'l' {
+			return 0, errInvalidCharacter(buf[cursor+3], "null", cursor)
+		}
+		cursor += 4
+		*(*interface{})(p) = nil
+		return cursor, nil
+	case '{':
+	default:
+		return 0, errExpected("{ character for union value", cursor)
+	}
+	// validate the object before looking for the key
+	if _, err := skipValue(buf, cursor, depth); err != nil {
+		return 0, err
+	}
+	typ, err := d.concreteType(buf, cursor, depth, 0)
+	if err != nil {
+		return 0, err
+	}
+	dec, err := decodeCompileToGetDecoderWithConfig(rtype_ptrTo(typ), ctx.option.config)
+	if err != nil {
+		return 0, err
+	}
+	v := unsafe_New(typ)
+	cursor, err = dec.decode(ctx, cursor, depth, v)
+	if err != nil {
+		return 0, err
+	}
+	d.set(typ, v, p)
+	return cursor, nil
+}
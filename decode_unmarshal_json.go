@@ -1,9 +1,13 @@
 package json
 
 import (
+	"context"
+	"reflect"
 	"unsafe"
 )
 
+var rawMessagePtrType = type2rtype(reflect.TypeOf((*RawMessage)(nil)))
+
 type unmarshalJSONDecoder struct {
 	typ        *rtype
 	structName string
@@ -28,6 +32,18 @@ func (d *unmarshalJSONDecoder) annotateError(cursor int64, err error) {
 	}
 }
 
+// callUnmarshalJSON calls UnmarshalJSON of v.
+// if v implements UnmarshalerContext, ctx is passed to it.
+func callUnmarshalJSON(ctx context.Context, v interface{}, data []byte) error {
+	if u, ok := v.(UnmarshalerContext); ok {
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		return u.UnmarshalJSON(ctx, data)
+	}
+	return v.(Unmarshaler).UnmarshalJSON(data)
+}
+
 func (d *unmarshalJSONDecoder) decodeStream(s *stream, depth int64, p unsafe.Pointer) error {
 	s.skipWhiteSpace()
 	start := s.cursor
@@ -42,14 +58,15 @@ func (d *unmarshalJSONDecoder) decodeStream(s *stream, depth int64, p unsafe.Poi
 		typ: d.typ,
 		ptr: p,
 	}))
-	if err := v.(Unmarshaler).UnmarshalJSON(dst); err != nil {
+	if err := callUnmarshalJSON(s.ctx, v, dst); err != nil {
 		d.annotateError(s.cursor, err)
 		return err
 	}
 	return nil
 }
 
-func (d *unmarshalJSONDecoder) decode(buf []byte, cursor, depth int64, p unsafe.Pointer) (int64, error) {
+func (d *unmarshalJSONDecoder) decode(ctx *runtimeContext, cursor, depth int64, p unsafe.Pointer) (int64, error) {
+	buf := ctx.buf
 	cursor = skipWhiteSpace(buf, cursor)
 	start := cursor
 	end, err := skipValue(buf, cursor, depth)
@@ -57,6 +74,11 @@ func (d *unmarshalJSONDecoder) decode(buf []byte, cursor, depth int64, p unsafe.
 		return 0, err
 	}
 	src := buf[start:end]
+	if d.typ == rawMessagePtrType && ctx.option.Flag&DecodeOptionBorrow != 0 {
+		// the capacity is limited not to overwrite the borrowed buffer by appending to the RawMessage
+		*(*RawMessage)(p) = src[:len(src):len(src)]
+		return end, nil
+	}
 	dst := make([]byte, len(src))
 	copy(dst, src)
 
@@ -64,7 +86,7 @@ func (d *unmarshalJSONDecoder) decode(buf []byte, cursor, depth int64, p unsafe.
 		typ: d.typ,
 		ptr: p,
 	}))
-	if err := v.(Unmarshaler).UnmarshalJSON(dst); err != nil {
+	if err := callUnmarshalJSON(ctx.ctx, v, dst); err != nil {
 		d.annotateError(cursor, err)
 		return 0, err
 	}
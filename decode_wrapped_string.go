@@ -38,14 +38,14 @@ func (d *wrappedStringDecoder) decodeStream(s *stream, depth int64, p unsafe.Poi
 	}
 	b := make([]byte, len(bytes)+1)
 	copy(b, bytes)
-	if _, err := d.dec.decode(b, 0, depth, p); err != nil {
+	if _, err := d.dec.decode(&runtimeContext{buf: b, option: s.option, ctx: s.ctx}, 0, depth, p); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (d *wrappedStringDecoder) decode(buf []byte, cursor, depth int64, p unsafe.Pointer) (int64, error) {
-	bytes, c, err := d.stringDecoder.decodeByte(buf, cursor)
+func (d *wrappedStringDecoder) decode(ctx *runtimeContext, cursor, depth int64, p unsafe.Pointer) (int64, error) {
+	bytes, c, err := d.stringDecoder.decodeContextByte(ctx, cursor)
 	if err != nil {
 		return 0, err
 	}
@@ -55,8 +55,12 @@ func (d *wrappedStringDecoder) decode(buf []byte, cursor, depth int64, p unsafe.
 		}
 		return c, nil
 	}
+	if ctx.option.Flag&DecodeOptionBorrow != 0 {
+		// not to overwrite the closing quote in the borrowed buffer
+		bytes = bytes[:len(bytes):len(bytes)]
+	}
 	bytes = append(bytes, nul)
-	if _, err := d.dec.decode(bytes, 0, depth, p); err != nil {
+	if _, err := d.dec.decode(&runtimeContext{buf: bytes, option: ctx.option, ctx: ctx.ctx}, 0, depth, p); err != nil {
 		return 0, err
 	}
 	return c, nil
@@ -1,7 +1,9 @@
 package json
 
 import (
+	"context"
 	"io"
+	"reflect"
 	"sync"
 	"unsafe"
 
@@ -20,21 +22,42 @@ type Encoder struct {
 	enabledHTMLEscape bool
 	prefix            string
 	indentStr         string
+	typeEncoders      *encoder.TypeEncoders
 }
 
 const (
 	bufSize = 1024
 )
 
-type EncodeOption int
+// EncodeOptionFlag is a flag of EncodeOption.
+type EncodeOptionFlag int
 
 const (
-	EncodeOptionHTMLEscape EncodeOption = 1 << iota
+	// EncodeOptionHTMLEscape escapes <, > and & in strings. It is set by default and cleared by Encoder.SetEscapeHTML(false).
+	EncodeOptionHTMLEscape EncodeOptionFlag = 1 << iota
+	// EncodeOptionIndent is set by MarshalIndent.
 	EncodeOptionIndent
+	// EncodeOptionUnorderedMap is set by UnorderedMap.
 	EncodeOptionUnorderedMap
+	// EncodeOptionDebug is set by Debug.
 	EncodeOptionDebug
 )
 
+// EncodeOption holds the flags and the configuration of an encoding.
+// It is set by the EncodeOptionFuncs given to the encoding.
+type EncodeOption struct {
+	Flag   EncodeOptionFlag
+	config encoder.CompileConfig
+}
+
+// apply applies optFuncs to opt.
+func (opt EncodeOption) apply(optFuncs []EncodeOptionFunc) EncodeOption {
+	for _, optFunc := range optFuncs {
+		optFunc(&opt)
+	}
+	return opt
+}
+
 var (
 	encRuntimeContextPool = sync.Pool{
 		New: func() interface{} {
@@ -52,6 +75,7 @@ func takeEncodeRuntimeContext() *encoder.RuntimeContext {
 }
 
 func releaseEncodeRuntimeContext(ctx *encoder.RuntimeContext) {
+	ctx.Context = nil
 	encRuntimeContextPool.Put(ctx)
 }
 
@@ -77,14 +101,24 @@ func (e *Encoder) EncodeWithOption(v interface{}, optFuncs ...EncodeOptionFunc)
 	return err
 }
 
+// EncodeContext call Encode with context.Context and EncodeOption.
+// The context is passed to the MarshalJSON method of values implementing MarshalerContext.
+func (e *Encoder) EncodeContext(ctx context.Context, v interface{}, optFuncs ...EncodeOptionFunc) error {
+	rctx := takeEncodeRuntimeContext()
+	rctx.Context = ctx
+
+	err := e.encodeWithOption(rctx, v, optFuncs...)
+
+	releaseEncodeRuntimeContext(rctx)
+	return err
+}
+
 func (e *Encoder) encodeWithOption(ctx *encoder.RuntimeContext, v interface{}, optFuncs ...EncodeOptionFunc) error {
-	var opt EncodeOption
+	opt := EncodeOption{config: encoder.CompileConfig{Encoders: e.typeEncoders}}
 	if e.enabledHTMLEscape {
-		opt |= EncodeOptionHTMLEscape
-	}
-	for _, optFunc := range optFuncs {
-		opt = optFunc(opt)
+		opt.Flag |= EncodeOptionHTMLEscape
 	}
+	opt = opt.apply(optFuncs)
 	var (
 		buf []byte
 		err error
@@ -109,6 +143,14 @@ func (e *Encoder) encodeWithOption(ctx *encoder.RuntimeContext, v interface{}, o
 	return nil
 }
 
+// RegisterTypeEncoder registers fn as the encoder of typ for the values encoded by e only.
+// It takes precedence over the encoder registered by the package-level RegisterTypeEncoder.
+// The Encoders that register the same function values for the same types share the compiled encoders,
+// so pass a function declared once ( e.g. a package-level function ) rather than a new closure for each Encoder.
+func (e *Encoder) RegisterTypeEncoder(typ reflect.Type, fn func(b []byte, v interface{}) ([]byte, error)) {
+	e.typeEncoders = e.typeEncoders.With(typ, fn)
+}
+
 // SetEscapeHTML specifies whether problematic HTML characters should be escaped inside JSON quoted strings.
 // The default behavior is to escape &, <, and > to \u0026, \u003c, and \u003e to avoid certain safety problems that can arise when embedding JSON in HTML.
 //
@@ -132,7 +174,8 @@ func (e *Encoder) SetIndent(prefix, indent string) {
 func marshal(v interface{}, opt EncodeOption) ([]byte, error) {
 	ctx := takeEncodeRuntimeContext()
 
-	buf, err := encode(ctx, v, opt|EncodeOptionHTMLEscape)
+	opt.Flag |= EncodeOptionHTMLEscape
+	buf, err := encode(ctx, v, opt)
 	if err != nil {
 		releaseEncodeRuntimeContext(ctx)
 		return nil, err
@@ -150,10 +193,30 @@ func marshal(v interface{}, opt EncodeOption) ([]byte, error) {
 	return copied, nil
 }
 
+func marshalContext(ctx context.Context, v interface{}, opt EncodeOption) ([]byte, error) {
+	rctx := takeEncodeRuntimeContext()
+	rctx.Context = ctx
+
+	opt.Flag |= EncodeOptionHTMLEscape
+	buf, err := encode(rctx, v, opt)
+	if err != nil {
+		releaseEncodeRuntimeContext(rctx)
+		return nil, err
+	}
+
+	buf = buf[:len(buf)-1]
+	copied := make([]byte, len(buf))
+	copy(copied, buf)
+
+	releaseEncodeRuntimeContext(rctx)
+	return copied, nil
+}
+
 func marshalNoEscape(v interface{}, opt EncodeOption) ([]byte, error) {
 	ctx := takeEncodeRuntimeContext()
 
-	buf, err := encodeNoEscape(ctx, v, opt|EncodeOptionHTMLEscape)
+	opt.Flag |= EncodeOptionHTMLEscape
+	buf, err := encodeNoEscape(ctx, v, opt)
 	if err != nil {
 		releaseEncodeRuntimeContext(ctx)
 		return nil, err
@@ -174,7 +237,8 @@ func marshalNoEscape(v interface{}, opt EncodeOption) ([]byte, error) {
 func marshalIndent(v interface{}, prefix, indent string, opt EncodeOption) ([]byte, error) {
 	ctx := takeEncodeRuntimeContext()
 
-	buf, err := encodeIndent(ctx, v, prefix, indent, opt|EncodeOptionHTMLEscape)
+	opt.Flag |= EncodeOptionHTMLEscape
+	buf, err := encodeIndent(ctx, v, prefix, indent, opt)
 	if err != nil {
 		releaseEncodeRuntimeContext(ctx)
 		return nil, err
@@ -199,7 +263,7 @@ func encode(ctx *encoder.RuntimeContext, v interface{}, opt EncodeOption) ([]byt
 	typ := header.typ
 
 	typeptr := uintptr(unsafe.Pointer(typ))
-	codeSet, err := encoder.CompileToGetCodeSet(typeptr)
+	codeSet, err := encodeCompileToGetCodeSet(typeptr, opt)
 	if err != nil {
 		return nil, err
 	}
@@ -227,7 +291,7 @@ func encodeNoEscape(ctx *encoder.RuntimeContext, v interface{}, opt EncodeOption
 	typ := header.typ
 
 	typeptr := uintptr(unsafe.Pointer(typ))
-	codeSet, err := encoder.CompileToGetCodeSet(typeptr)
+	codeSet, err := encodeCompileToGetCodeSet(typeptr, opt)
 	if err != nil {
 		return nil, err
 	}
@@ -254,7 +318,7 @@ func encodeIndent(ctx *encoder.RuntimeContext, v interface{}, prefix, indent str
 	typ := header.typ
 
 	typeptr := uintptr(unsafe.Pointer(typ))
-	codeSet, err := encoder.CompileToGetCodeSet(typeptr)
+	codeSet, err := encodeCompileToGetCodeSet(typeptr, opt)
 	if err != nil {
 		return nil, err
 	}
@@ -273,21 +337,29 @@ func encodeIndent(ctx *encoder.RuntimeContext, v interface{}, prefix, indent str
 	return buf, nil
 }
 
+func encodeCompileToGetCodeSet(typeptr uintptr, opt EncodeOption) (*encoder.OpcodeSet, error) {
+	if opt.config == (encoder.CompileConfig{}) {
+		return encoder.CompileToGetCodeSet(typeptr)
+	}
+	config := opt.config
+	return encoder.CompileToGetCodeSetWithConfig(typeptr, &config)
+}
+
 func encodeRunCode(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet, opt EncodeOption) ([]byte, error) {
-	if (opt & EncodeOptionDebug) != 0 {
-		return vm_debug.Run(ctx, b, codeSet, encoder.Option(opt))
+	if (opt.Flag & EncodeOptionDebug) != 0 {
+		return vm_debug.Run(ctx, b, codeSet, encoder.Option(opt.Flag))
 	}
-	if (opt & EncodeOptionHTMLEscape) != 0 {
-		return vm_escaped.Run(ctx, b, codeSet, encoder.Option(opt))
+	if (opt.Flag & EncodeOptionHTMLEscape) != 0 {
+		return vm_escaped.Run(ctx, b, codeSet, encoder.Option(opt.Flag))
 	}
-	return vm.Run(ctx, b, codeSet, encoder.Option(opt))
+	return vm.Run(ctx, b, codeSet, encoder.Option(opt.Flag))
 }
 
 func encodeRunIndentCode(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet, prefix, indent string, opt EncodeOption) ([]byte, error) {
 	ctx.Prefix = []byte(prefix)
 	ctx.IndentStr = []byte(indent)
-	if (opt & EncodeOptionHTMLEscape) != 0 {
-		return vm_escaped_indent.Run(ctx, b, codeSet, encoder.Option(opt))
+	if (opt.Flag & EncodeOptionHTMLEscape) != 0 {
+		return vm_escaped_indent.Run(ctx, b, codeSet, encoder.Option(opt.Flag))
 	}
-	return vm_indent.Run(ctx, b, codeSet, encoder.Option(opt))
+	return vm_indent.Run(ctx, b, codeSet, encoder.Option(opt.Flag))
 }
@@ -2,6 +2,7 @@ package json_test
 
 import (
 	"bytes"
+	"context"
 	"encoding"
 	stdjson "encoding/json"
 	"errors"
@@ -11,6 +12,7 @@ import (
 	"reflect"
 	"regexp"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -1805,3 +1807,949 @@ func TestIssue104(t *testing.T) {
 		t.Fatalf("expect %q but got %q", string(expect), string(got))
 	}
 }
+
+type marshalContextKey struct{}
+
+type recursiveMarshaler struct{}
+
+func (r *recursiveMarshaler) MarshalJSON(ctx context.Context) ([]byte, error) {
+	if ctx.Value(marshalContextKey{}) != "hello" {
+		return nil, fmt.Errorf("failed to propagate parent context.Context")
+	}
+	return []byte(`"hello"`), nil
+}
+
+type recursiveMarshalerValue struct{}
+
+func (r recursiveMarshalerValue) MarshalJSON(ctx context.Context) ([]byte, error) {
+	if ctx.Value(marshalContextKey{}) != "hello" {
+		return nil, fmt.Errorf("failed to propagate parent context.Context")
+	}
+	return []byte(`"world"`), nil
+}
+
+func TestMarshalContext(t *testing.T) {
+	ctx := context.WithValue(context.Background(), marshalContextKey{}, "hello")
+	t.Run("MarshalerContext", func(t *testing.T) {
+		b, err := json.MarshalContext(ctx, &recursiveMarshaler{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != `"hello"` {
+			t.Fatalf("failed to encode: %q", string(b))
+		}
+	})
+	t.Run("struct field", func(t *testing.T) {
+		v := struct {
+			A recursiveMarshalerValue   `json:"a"`
+			B *recursiveMarshaler       `json:"b"`
+			C []recursiveMarshalerValue `json:"c"`
+		}{
+			B: &recursiveMarshaler{},
+			C: []recursiveMarshalerValue{{}},
+		}
+		b, err := json.MarshalContext(ctx, v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != `{"a":"world","b":"hello","c":["world"]}` {
+			t.Fatalf("failed to encode: %q", string(b))
+		}
+	})
+	t.Run("without context", func(t *testing.T) {
+		if _, err := json.Marshal(&recursiveMarshaler{}); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+	t.Run("Encoder.EncodeContext", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		v := struct {
+			A *recursiveMarshaler `json:"a"`
+		}{A: &recursiveMarshaler{}}
+		if err := enc.EncodeContext(ctx, v); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != "{\n  \"a\": \"hello\"\n}\n" {
+			t.Fatalf("failed to encode: %q", buf.String())
+		}
+	})
+}
+
+func TestMarshalWithFields(t *testing.T) {
+	type owner struct {
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	}
+	type Base struct {
+		ID      int    `json:"id"`
+		Created string `json:"created"`
+	}
+	type item struct {
+		Base
+		Name   string        `json:"name"`
+		Owner  *owner        `json:"owner"`
+		Tags   []string      `json:"tags"`
+		Owners []owner       `json:"owners"`
+		Any    interface{}   `json:"any"`
+		Next   *item         `json:"next,omitempty"`
+		Values []interface{} `json:"values,omitempty"`
+	}
+	v := &item{
+		Base:   Base{ID: 1, Created: "today"},
+		Name:   "foo",
+		Owner:  &owner{Name: "bob", Email: "bob@example.com"},
+		Tags:   []string{"a"},
+		Owners: []owner{{Name: "alice", Email: "alice@example.com"}},
+		Any:    owner{Name: "carol", Email: "carol@example.com"},
+		Next:   &item{Base: Base{ID: 2}, Name: "bar"},
+	}
+	tests := []struct {
+		name     string
+		fields   []string
+		expected string
+	}{
+		{"top level", []string{"id", "name"}, `{"id":1,"name":"foo"}`},
+		{"nested", []string{"id", "owner.email"}, `{"id":1,"owner":{"email":"bob@example.com"}}`},
+		{"whole value wins", []string{"owner.email", "owner"}, `{"owner":{"name":"bob","email":"bob@example.com"}}`},
+		{"slice", []string{"owners.name", "tags"}, `{"tags":["a"],"owners":[{"name":"alice"}]}`},
+		{"interface", []string{"any.name"}, `{"any":{"name":"carol"}}`},
+		{"recursive type", []string{"name", "next.id"}, `{"name":"foo","next":{"id":2}}`},
+		{"embedded", []string{"created"}, `{"created":"today"}`},
+		{"nothing", []string{"unknown"}, `{}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b, err := json.MarshalWithOption(v, json.Fields(test.fields...))
+			assertErr(t, err)
+			assertEq(t, "fields", test.expected, string(b))
+		})
+	}
+	t.Run("without fields", func(t *testing.T) {
+		b, err := json.MarshalWithOption(&item{Name: "foo", Next: &item{Name: "bar"}})
+		assertErr(t, err)
+		assertEq(t, "fields", `{"id":0,"created":"","name":"foo","owner":null,"tags":null,"owners":null,"any":null,"next":{"id":0,"created":"","name":"bar","owner":null,"tags":null,"owners":null,"any":null}}`, string(b))
+	})
+	t.Run("indent", func(t *testing.T) {
+		b, err := json.MarshalIndentWithOption(v, "", "  ", json.Fields("id", "owner.name"))
+		assertErr(t, err)
+		assertEq(t, "fields", "{\n  \"id\": 1,\n  \"owner\": {\n    \"name\": \"bob\"\n  }\n}", string(b))
+	})
+	t.Run("Encoder", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		assertErr(t, enc.EncodeWithOption(v, json.Fields("name")))
+		assertEq(t, "fields", "{\"name\":\"foo\"}\n", buf.String())
+	})
+	t.Run("recursive embedded struct", func(t *testing.T) {
+		type self struct {
+			*self
+			A int `json:"a"`
+		}
+		b, err := json.MarshalWithOption(self{self: &self{A: 2}, A: 1}, json.Fields("a"))
+		assertErr(t, err)
+		assertEq(t, "fields", `{"a":1}`, string(b))
+	})
+	t.Run("with a custom option", func(t *testing.T) {
+		custom := func(opt *json.EncodeOption) {
+			opt.Flag = json.EncodeOptionHTMLEscape | json.EncodeOptionUnorderedMap
+		}
+		b, err := json.MarshalWithOption(v, json.Fields("id"), custom)
+		assertErr(t, err)
+		assertEq(t, "fields", `{"id":1}`, string(b))
+	})
+}
+
+func TestMarshalWithFieldsMap(t *testing.T) {
+	v := map[string]interface{}{
+		"id":   1,
+		"name": "foo",
+		"meta": map[string]map[string]int{
+			"a": {"x": 1, "y": 2},
+			"b": {"x": 3, "y": 4},
+		},
+	}
+	tests := []struct {
+		fields   []string
+		expected string
+	}{
+		{[]string{"id", "name"}, `{"id":1,"name":"foo"}`},
+		{[]string{"meta.a.x"}, `{"meta":{"a":{"x":1}}}`},
+		{[]string{"meta.a.x", "meta.b.y"}, `{"meta":{"a":{"x":1,"y":2},"b":{"x":3,"y":4}}}`},
+		{[]string{"meta.c"}, `{"meta":{}}`},
+	}
+	for _, test := range tests {
+		b, err := json.MarshalWithOption(v, json.Fields(test.fields...))
+		assertErr(t, err)
+		assertEq(t, "fields", test.expected, string(b))
+
+		b, err = json.MarshalWithOption(v, json.Fields(test.fields...), json.UnorderedMap())
+		assertErr(t, err)
+		if len(b) != len(test.expected) {
+			t.Fatalf("unexpected result with UnorderedMap: %s", string(b))
+		}
+	}
+}
+
+func TestMarshalWithFieldNaming(t *testing.T) {
+	type owner struct {
+		UserID   int
+		HTTPAddr string
+	}
+	type T struct {
+		FirstName string
+		Tagged    string `json:"TAGGED"`
+		OmitEmpty string `json:",omitempty"`
+		Owner     *owner
+		Any       interface{}
+	}
+	v := &T{FirstName: "foo", Tagged: "bar", Owner: &owner{UserID: 1, HTTPAddr: "addr"}, Any: owner{UserID: 2}}
+	tests := []struct {
+		naming   *json.NamingStrategy
+		expected string
+	}{
+		{json.SnakeCase, `{"first_name":"foo","TAGGED":"bar","owner":{"user_id":1,"http_addr":"addr"},"any":{"user_id":2,"http_addr":""}}`},
+		{json.CamelCase, `{"firstName":"foo","TAGGED":"bar","owner":{"userID":1,"httpAddr":"addr"},"any":{"userID":2,"httpAddr":""}}`},
+		{json.KebabCase, `{"first-name":"foo","TAGGED":"bar","owner":{"user-id":1,"http-addr":"addr"},"any":{"user-id":2,"http-addr":""}}`},
+		{json.NewNamingStrategy(strings.ToUpper), `{"FIRSTNAME":"foo","TAGGED":"bar","OWNER":{"USERID":1,"HTTPADDR":"addr"},"ANY":{"USERID":2,"HTTPADDR":""}}`},
+	}
+	for _, test := range tests {
+		b, err := json.MarshalWithOption(v, json.EncodeFieldNaming(test.naming))
+		assertErr(t, err)
+		assertEq(t, "naming", test.expected, string(b))
+	}
+	t.Run("with fields", func(t *testing.T) {
+		b, err := json.MarshalWithOption(v, json.EncodeFieldNaming(json.SnakeCase), json.Fields("first_name", "owner.user_id"))
+		assertErr(t, err)
+		assertEq(t, "naming", `{"first_name":"foo","owner":{"user_id":1}}`, string(b))
+	})
+	t.Run("default naming is not changed", func(t *testing.T) {
+		b, err := json.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "naming", `{"FirstName":"foo","TAGGED":"bar","Owner":{"UserID":1,"HTTPAddr":"addr"},"Any":{"UserID":2,"HTTPAddr":""}}`, string(b))
+	})
+}
+
+type registeredPoint struct {
+	X, Y int
+}
+
+// MarshalJSON is overridden by the registered encoder
+func (registeredPoint) MarshalJSON() ([]byte, error) {
+	return []byte(`"marshaler"`), nil
+}
+
+func init() {
+	json.RegisterTypeEncoder(reflect.TypeOf(registeredPoint{}), func(b []byte, v interface{}) ([]byte, error) {
+		p := v.(registeredPoint)
+		b = append(b, '[')
+		b = strconv.AppendInt(b, int64(p.X), 10)
+		b = append(b, ',')
+		b = strconv.AppendInt(b, int64(p.Y), 10)
+		return append(b, ']'), nil
+	})
+}
+
+func TestRegisterTypeEncoder(t *testing.T) {
+	type T struct {
+		Point    registeredPoint
+		PointPtr *registeredPoint
+		NilPtr   *registeredPoint
+		Points   []registeredPoint
+		Any      interface{}
+	}
+	v := T{
+		Point:    registeredPoint{X: 1, Y: 2},
+		PointPtr: &registeredPoint{X: 3, Y: 4},
+		Points:   []registeredPoint{{X: 5, Y: 6}},
+		Any:      registeredPoint{X: 7, Y: 8},
+	}
+	t.Run("value", func(t *testing.T) {
+		b, err := json.Marshal(registeredPoint{X: 1, Y: 2})
+		assertErr(t, err)
+		assertEq(t, "point", `[1,2]`, string(b))
+		b, err = json.Marshal(&registeredPoint{X: 1, Y: 2})
+		assertErr(t, err)
+		assertEq(t, "pointer", `[1,2]`, string(b))
+	})
+	t.Run("struct", func(t *testing.T) {
+		b, err := json.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "struct", `{"Point":[1,2],"PointPtr":[3,4],"NilPtr":null,"Points":[[5,6]],"Any":[7,8]}`, string(b))
+		b, err = json.Marshal(&v)
+		assertErr(t, err)
+		assertEq(t, "struct pointer", `{"Point":[1,2],"PointPtr":[3,4],"NilPtr":null,"Points":[[5,6]],"Any":[7,8]}`, string(b))
+	})
+	t.Run("indent", func(t *testing.T) {
+		b, err := json.MarshalIndent(struct{ Points []registeredPoint }{[]registeredPoint{{X: 1, Y: 2}}}, "", "  ")
+		assertErr(t, err)
+		assertEq(t, "indent", "{\n  \"Points\": [\n    [\n      1,\n      2\n    ]\n  ]\n}", string(b))
+	})
+	t.Run("error", func(t *testing.T) {
+		type failure struct{}
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.RegisterTypeEncoder(reflect.TypeOf(failure{}), func(b []byte, v interface{}) ([]byte, error) {
+			return nil, errors.New("failure")
+		})
+		err := enc.Encode(failure{})
+		var merr *json.MarshalerError
+		if !errors.As(err, &merr) {
+			t.Fatalf("expected *json.MarshalerError but got %T", err)
+		}
+	})
+	t.Run("encoder scoped", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.RegisterTypeEncoder(reflect.TypeOf(registeredPoint{}), func(b []byte, v interface{}) ([]byte, error) {
+			p := v.(registeredPoint)
+			return append(b, fmt.Sprintf(`{"x":%d,"y":%d}`, p.X, p.Y)...), nil
+		})
+		assertErr(t, enc.Encode(v))
+		assertEq(t, "encoder", `{"Point":{"x":1,"y":2},"PointPtr":{"x":3,"y":4},"NilPtr":null,"Points":[{"x":5,"y":6}],"Any":{"x":7,"y":8}}`+"\n", buf.String())
+
+		b, err := json.Marshal(v.Point)
+		assertErr(t, err)
+		assertEq(t, "other encodings are not changed", `[1,2]`, string(b))
+	})
+	t.Run("registered after encoding", func(t *testing.T) {
+		type late struct{ X int }
+		type T struct{ Late late }
+		b, err := json.Marshal(T{late{X: 1}})
+		assertErr(t, err)
+		assertEq(t, "before", `{"Late":{"X":1}}`, string(b))
+		b, err = json.MarshalWithOption(T{late{X: 1}}, json.Fields("Late"))
+		assertErr(t, err)
+		assertEq(t, "before with fields", `{"Late":{"X":1}}`, string(b))
+
+		json.RegisterTypeEncoder(reflect.TypeOf(late{}), func(b []byte, v interface{}) ([]byte, error) {
+			return strconv.AppendInt(b, int64(v.(late).X), 10), nil
+		})
+		b, err = json.Marshal(T{late{X: 1}})
+		assertErr(t, err)
+		assertEq(t, "after", `{"Late":1}`, string(b))
+		b, err = json.MarshalWithOption(T{late{X: 1}}, json.Fields("Late"))
+		assertErr(t, err)
+		assertEq(t, "after with fields", `{"Late":1}`, string(b))
+	})
+}
+
+type zeroByMethod struct {
+	V int
+}
+
+func (z *zeroByMethod) IsZero() bool { return z.V <= 0 }
+
+func TestMarshalOmitZero(t *testing.T) {
+	type inner struct {
+		A int
+		B string
+	}
+	type T struct {
+		Time     time.Time      `json:"time,omitzero"`
+		TimePtr  *time.Time     `json:"timePtr,omitzero"`
+		Inner    inner          `json:"inner,omitzero"`
+		InnerPtr *inner         `json:"innerPtr,omitzero"`
+		Array    [2]int         `json:"array,omitzero"`
+		Slice    []int          `json:"slice,omitzero"`
+		Empty    []int          `json:"empty,omitempty,omitzero"`
+		Iface    interface{}    `json:"iface,omitzero"`
+		Int      int            `json:"int,omitzero"`
+		Float    float64        `json:"float,omitzero"`
+		String   string         `json:"string,omitzero"`
+		Method   zeroByMethod   `json:"method,omitzero"`
+		MethodP  *zeroByMethod  `json:"methodPtr,omitzero"`
+		Map      map[string]int `json:"map,omitzero"`
+	}
+	zeroTime := time.Time{}
+	tests := []struct {
+		name     string
+		v        T
+		expected string
+	}{
+		{
+			name:     "zero",
+			v:        T{TimePtr: &zeroTime, Slice: nil, Empty: []int{}, Method: zeroByMethod{V: -1}, MethodP: &zeroByMethod{}},
+			expected: `{}`,
+		},
+		{
+			name: "not zero",
+			v: T{
+				Time:     time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC),
+				InnerPtr: &inner{},
+				Inner:    inner{B: "b"},
+				Array:    [2]int{0, 1},
+				Slice:    []int{},
+				Empty:    []int{1},
+				Iface:    0,
+				Int:      1,
+				Float:    0.5,
+				String:   "s",
+				Method:   zeroByMethod{V: 1},
+				MethodP:  &zeroByMethod{V: 2},
+			},
+			expected: `{"time":"2021-01-02T03:04:05Z","inner":{"A":0,"B":"b"},"innerPtr":{"A":0,"B":""},"array":[0,1],"slice":[],"empty":[1],"iface":0,"int":1,"float":0.5,"string":"s","method":{"V":1},"methodPtr":{"V":2}}`,
+		},
+		{
+			name:     "head",
+			v:        T{Inner: inner{A: 1}},
+			expected: `{"inner":{"A":1,"B":""}}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, marshal := range []func(interface{}) ([]byte, error){
+				json.Marshal,
+				json.MarshalNoEscape,
+				func(v interface{}) ([]byte, error) { return json.MarshalWithOption(v, json.Debug()) },
+			} {
+				b, err := marshal(test.v)
+				assertErr(t, err)
+				assertEq(t, "value", test.expected, string(b))
+				b, err = marshal(&test.v)
+				assertErr(t, err)
+				assertEq(t, "pointer", test.expected, string(b))
+			}
+			b, err := json.MarshalIndent(test.v, "", "")
+			assertErr(t, err)
+			expected, err := json.MarshalIndent(json.RawMessage(test.expected), "", "")
+			assertErr(t, err)
+			assertEq(t, "indent", string(expected), string(b))
+		})
+	}
+	t.Run("single field", func(t *testing.T) {
+		b, err := json.Marshal(struct {
+			P *int `json:"p,omitzero"`
+		}{})
+		assertErr(t, err)
+		assertEq(t, "pointer", `{}`, string(b))
+		b, err = json.Marshal(struct {
+			M map[string]int `json:"m,omitzero"`
+		}{})
+		assertErr(t, err)
+		assertEq(t, "map", `{}`, string(b))
+		b, err = json.Marshal(&struct {
+			I inner `json:"i,omitzero"`
+		}{})
+		assertErr(t, err)
+		assertEq(t, "struct", `{}`, string(b))
+		n := 1
+		b, err = json.Marshal(struct {
+			P *int `json:"p,omitzero"`
+		}{&n})
+		assertErr(t, err)
+		assertEq(t, "not nil pointer", `{"p":1}`, string(b))
+		b, err = json.Marshal(struct {
+			P *[]int `json:"p,omitzero"`
+		}{&[]int{1}})
+		assertErr(t, err)
+		assertEq(t, "pointer to slice", `{"p":[1]}`, string(b))
+		b, err = json.Marshal(struct {
+			M zeroByPointer `json:"m,omitzero"`
+		}{zeroByPointer{&n}})
+		assertErr(t, err)
+		assertEq(t, "struct of pointer", `{"m":{"P":1}}`, string(b))
+		zero := 0
+		b, err = json.Marshal(struct {
+			M zeroByPointer `json:"m,omitzero"`
+		}{zeroByPointer{&zero}})
+		assertErr(t, err)
+		assertEq(t, "zero struct of pointer", `{}`, string(b))
+	})
+	t.Run("negative zero", func(t *testing.T) {
+		negativeZero := math.Copysign(0, -1)
+		b, err := json.Marshal(struct {
+			Zero  float64 `json:"zero,omitzero"`
+			Empty float64 `json:"empty,omitempty"`
+			Both  float32 `json:"both,omitempty,omitzero"`
+		}{negativeZero, negativeZero, float32(negativeZero)})
+		assertErr(t, err)
+		assertEq(t, "float", `{"zero":-0}`, string(b))
+	})
+	t.Run("string option", func(t *testing.T) {
+		type T struct {
+			Int    int         `json:"int,string,omitzero"`
+			Uint   uint8       `json:"uint,omitzero,string"`
+			Float  float64     `json:"float,omitempty,string"`
+			Bool   bool        `json:"bool,string,omitempty"`
+			String string      `json:"string,string,omitzero"`
+			Number json.Number `json:"number,string,omitzero"`
+			Ptr    *int        `json:"ptr,string,omitzero"`
+		}
+		n := 3
+		for _, test := range []struct {
+			name     string
+			v        T
+			expected string
+		}{
+			{name: "zero", v: T{}, expected: `{}`},
+			{
+				name:     "not zero",
+				v:        T{Int: -1, Uint: 2, Float: 0.5, Bool: true, String: `a"b`, Number: "12", Ptr: &n},
+				expected: `{"int":"-1","uint":"2","float":"0.5","bool":"true","string":"\"a\\\"b\"","number":"12","ptr":"3"}`,
+			},
+		} {
+			t.Run(test.name, func(t *testing.T) {
+				b, err := json.Marshal(test.v)
+				assertErr(t, err)
+				assertEq(t, "value", test.expected, string(b))
+				b, err = json.MarshalIndent(&test.v, "", "")
+				assertErr(t, err)
+				expected, err := json.MarshalIndent(json.RawMessage(test.expected), "", "")
+				assertErr(t, err)
+				assertEq(t, "indent", string(expected), string(b))
+
+				var decoded T
+				assertErr(t, json.Unmarshal([]byte(test.expected), &decoded))
+				assertEq(t, "round trip", fmt.Sprint(test.v.Int, test.v.String, test.v.Ptr != nil), fmt.Sprint(decoded.Int, decoded.String, decoded.Ptr != nil))
+			})
+		}
+		b, err := json.Marshal(struct {
+			Ptr *int `json:"ptr,string,omitzero"`
+		}{&n})
+		assertErr(t, err)
+		assertEq(t, "single field", `{"ptr":"3"}`, string(b))
+	})
+}
+
+func TestMarshalStringOmitEmpty(t *testing.T) {
+	// like encoding/json, the string option doesn't disable omitempty
+	type T struct {
+		Int    int         `json:"int,string,omitempty"`
+		Float  float64     `json:"float,string,omitempty"`
+		Bool   bool        `json:"bool,string,omitempty"`
+		String string      `json:"string,string,omitempty"`
+		Number json.Number `json:"number,string,omitempty"`
+		IntPtr *int        `json:"intPtr,string,omitempty"`
+		StrPtr *string     `json:"strPtr,string,omitempty"`
+	}
+	n, s := 0, "<a>"
+	for _, v := range []T{
+		{},
+		{Float: math.Copysign(0, -1)},
+		{Int: 1, Float: 1.5, Bool: true, String: `"<a>"`, Number: "2", IntPtr: &n, StrPtr: &s},
+	} {
+		expected, err := stdjson.Marshal(v)
+		assertErr(t, err)
+		b, err := json.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "string omitempty", string(expected), string(b))
+		b, err = json.Marshal(&v)
+		assertErr(t, err)
+		assertEq(t, "string omitempty of pointer", string(expected), string(b))
+
+		expected, err = stdjson.MarshalIndent(v, "", "  ")
+		assertErr(t, err)
+		b, err = json.MarshalIndent(v, "", "  ")
+		assertErr(t, err)
+		assertEq(t, "string omitempty with indent", string(expected), string(b))
+	}
+}
+
+// zeroByPointer is a struct that is stored directly in an interface.
+type zeroByPointer struct{ P *int }
+
+func (z zeroByPointer) IsZero() bool { return z.P == nil || *z.P == 0 }
+
+// fixedEntries is encoded as the same entries regardless of its content.
+type fixedEntries map[string]int
+
+func (fixedEntries) MarshalJSON() ([]byte, error) {
+	return []byte(`{"x":1,"y":[1,2]}`), nil
+}
+
+func TestMarshalInline(t *testing.T) {
+	type T struct {
+		ID    int          `json:"id"`
+		Extra fixedEntries `json:",inline"`
+		Name  string       `json:"name"`
+	}
+	type U struct {
+		Extra fixedEntries `json:",unknown"`
+	}
+	tests := []struct {
+		name     string
+		v        interface{}
+		expected string
+	}{
+		{
+			name:     "after known fields",
+			v:        T{ID: 1, Extra: fixedEntries{"q": 0}, Name: "n"},
+			expected: `{"id":1,"name":"n","x":1,"y":[1,2]}`,
+		},
+		{
+			name:     "nil",
+			v:        T{ID: 1, Name: "n"},
+			expected: `{"id":1,"name":"n"}`,
+		},
+		{
+			name:     "head",
+			v:        U{Extra: fixedEntries{"q": 0}},
+			expected: `{"x":1,"y":[1,2]}`,
+		},
+		{
+			name:     "head nil",
+			v:        U{},
+			expected: `{}`,
+		},
+		{
+			name:     "nested",
+			v:        []U{{Extra: fixedEntries{"q": 0}}, {}},
+			expected: `[{"x":1,"y":[1,2]},{}]`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, marshal := range []func(interface{}) ([]byte, error){
+				json.Marshal,
+				json.MarshalNoEscape,
+				func(v interface{}) ([]byte, error) { return json.MarshalWithOption(v, json.Debug()) },
+			} {
+				b, err := marshal(test.v)
+				assertErr(t, err)
+				assertEq(t, "value", test.expected, string(b))
+			}
+			b, err := json.MarshalIndent(test.v, ">", "  ")
+			assertErr(t, err)
+			expected, err := json.MarshalIndent(json.RawMessage(test.expected), ">", "  ")
+			assertErr(t, err)
+			assertEq(t, "indent", string(expected), string(b))
+		})
+	}
+	t.Run("empty map", func(t *testing.T) {
+		b, err := json.Marshal(struct {
+			A     int            `json:"a"`
+			Extra map[string]int `json:",inline"`
+		}{A: 1, Extra: map[string]int{}})
+		assertErr(t, err)
+		assertEq(t, "empty", `{"a":1}`, string(b))
+	})
+}
+
+func TestMarshalInlineFieldKeys(t *testing.T) {
+	type Base struct {
+		Kind string `json:"kind"`
+	}
+	type T struct {
+		*Base
+		ID    int            `json:"id"`
+		Name  string         `json:"name,omitempty"`
+		Extra map[string]int `json:",inline"`
+	}
+	v := T{Base: &Base{Kind: "k"}, ID: 1, Extra: map[string]int{"id": 2, "kind": 3, "name": 4, "x": 5}}
+	for _, opts := range [][]json.EncodeOptionFunc{
+		nil,
+		{json.Debug()},
+		{json.UnorderedMap()},
+	} {
+		b, err := json.MarshalWithOption(v, opts...)
+		assertErr(t, err)
+		assertEq(t, "value", `{"kind":"k","id":1,"x":5}`, string(b))
+	}
+	b, err := json.MarshalIndent(&v, "", "")
+	assertErr(t, err)
+	assertEq(t, "indent", "{\n\"kind\": \"k\",\n\"id\": 1,\n\"x\": 5\n}", string(b))
+	b, err = json.MarshalWithOption(v, json.Fields("id", "Extra.id", "Extra.x"))
+	assertErr(t, err)
+	assertEq(t, "fields", `{"id":1,"x":5}`, string(b))
+	b, err = json.Marshal(T{ID: 1, Extra: map[string]int{"id": 2}})
+	assertErr(t, err)
+	assertEq(t, "all colliding", `{"id":1}`, string(b))
+}
+
+func TestMarshalTagKey(t *testing.T) {
+	type inner struct {
+		Code string `json:"code" api:"c"`
+	}
+	type T struct {
+		ID       int    `json:"id" api:"identifier"`
+		Internal string `json:"internal" api:"-"`
+		Name     string `json:"name" api:",omitempty"`
+		NoTag    int
+		Inner    inner       `json:"inner" api:"in"`
+		Ptr      *inner      `json:"ptr" api:"p,omitempty"`
+		Any      interface{} `json:"any" api:"any"`
+	}
+	v := T{ID: 1, Internal: "x", NoTag: 2, Inner: inner{Code: "a"}, Any: inner{Code: "b"}}
+	t.Run("json", func(t *testing.T) {
+		b, err := json.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "json", `{"id":1,"internal":"x","name":"","NoTag":2,"inner":{"code":"a"},"ptr":null,"any":{"code":"b"}}`, string(b))
+	})
+	t.Run("api", func(t *testing.T) {
+		for _, opts := range [][]json.EncodeOptionFunc{
+			{json.EncodeTagKey("api")},
+			{json.EncodeTagKey("api"), json.Debug()},
+			{json.EncodeTagKey("api"), json.UnorderedMap()},
+		} {
+			b, err := json.MarshalWithOption(v, opts...)
+			assertErr(t, err)
+			assertEq(t, "api", `{"identifier":1,"NoTag":2,"in":{"c":"a"},"any":{"c":"b"}}`, string(b))
+		}
+		b, err := json.MarshalIndentWithOption(&v, "", "", json.EncodeTagKey("api"))
+		assertErr(t, err)
+		assertEq(t, "indent", "{\n\"identifier\": 1,\n\"NoTag\": 2,\n\"in\": {\n\"c\": \"a\"\n},\n\"any\": {\n\"c\": \"b\"\n}\n}", string(b))
+	})
+	t.Run("cached per key", func(t *testing.T) {
+		b, err := json.MarshalWithOption(inner{Code: "a"}, json.EncodeTagKey("api"))
+		assertErr(t, err)
+		assertEq(t, "api", `{"c":"a"}`, string(b))
+		b, err = json.MarshalWithOption(inner{Code: "a"}, json.EncodeTagKey("db"))
+		assertErr(t, err)
+		assertEq(t, "db", `{"Code":"a"}`, string(b))
+		b, err = json.Marshal(inner{Code: "a"})
+		assertErr(t, err)
+		assertEq(t, "json", `{"code":"a"}`, string(b))
+	})
+}
+
+func TestMarshalMapSlice(t *testing.T) {
+	inner := json.MapSlice{{Key: "z", Value: 1}, {Key: "a", Value: []int{1, 2}}}
+	v := json.MapSlice{
+		{Key: "b", Value: "x"},
+		{Key: "a", Value: 1.5},
+		{Key: "n", Value: nil},
+		{Key: "inner", Value: inner},
+		{Key: "empty", Value: json.MapSlice{}},
+	}
+	t.Run("compact", func(t *testing.T) {
+		expected := `{"b":"x","a":1.5,"n":null,"inner":{"z":1,"a":[1,2]},"empty":{}}`
+		for _, opts := range [][]json.EncodeOptionFunc{nil, {json.Debug()}} {
+			b, err := json.MarshalWithOption(v, opts...)
+			assertErr(t, err)
+			assertEq(t, "value", expected, string(b))
+			b, err = json.MarshalWithOption(&v, opts...)
+			assertErr(t, err)
+			assertEq(t, "ptr", expected, string(b))
+		}
+	})
+	t.Run("escape", func(t *testing.T) {
+		v := json.MapSlice{{Key: "<k>", Value: "<v>"}}
+		b, err := json.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "escape", `{"\u003ck\u003e":"\u003cv\u003e"}`, string(b))
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		assertErr(t, enc.Encode(v))
+		assertEq(t, "no escape", "{\"<k>\":\"<v>\"}\n", buf.String())
+	})
+	t.Run("indent", func(t *testing.T) {
+		b, err := json.MarshalIndent(json.MapSlice{{Key: "b", Value: 1}, {Key: "a", Value: inner}, {Key: "e", Value: json.MapSlice{}}}, "", "  ")
+		assertErr(t, err)
+		assertEq(t, "indent", `{
+  "b": 1,
+  "a": {
+    "z": 1,
+    "a": [
+      1,
+      2
+    ]
+  },
+  "e": {}
+}`, string(b))
+	})
+	t.Run("nil", func(t *testing.T) {
+		b, err := json.Marshal(json.MapSlice(nil))
+		assertErr(t, err)
+		assertEq(t, "nil", `null`, string(b))
+	})
+	t.Run("struct field", func(t *testing.T) {
+		type T struct {
+			A json.MapSlice
+			B *json.MapSlice
+			C json.MapSlice  `json:",omitempty"`
+			D *json.MapSlice `json:",omitempty"`
+			E interface{}
+		}
+		b, err := json.Marshal(T{A: inner, B: &inner, C: json.MapSlice{}, E: inner})
+		assertErr(t, err)
+		assertEq(t, "struct", `{"A":{"z":1,"a":[1,2]},"B":{"z":1,"a":[1,2]},"E":{"z":1,"a":[1,2]}}`, string(b))
+		b, err = json.Marshal(struct{ P *json.MapSlice }{P: &inner})
+		assertErr(t, err)
+		assertEq(t, "single ptr field", `{"P":{"z":1,"a":[1,2]}}`, string(b))
+	})
+	t.Run("cycle", func(t *testing.T) {
+		v := json.MapSlice{{Key: "self"}}
+		v[0].Value = v
+		_, err := json.Marshal(v)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
+type plainShape interface {
+	Area() float64
+}
+
+func TestMarshalNonEmptyInterface(t *testing.T) {
+	var shape plainShape = &unionRect{Width: 2, Height: 3}
+	var nilShape plainShape
+	var empty interface{} = 1
+	tests := []struct {
+		name     string
+		v        interface{}
+		expected string
+	}{
+		{name: "field", v: struct{ S plainShape }{shape}, expected: `{"S":{"width":2,"height":3}}`},
+		{name: "fields", v: struct {
+			S plainShape
+			N int
+		}{shape, 1}, expected: `{"S":{"width":2,"height":3},"N":1}`},
+		{name: "nil field", v: struct{ S plainShape }{}, expected: `{"S":null}`},
+		{name: "pointer field", v: struct{ S *plainShape }{&shape}, expected: `{"S":{"width":2,"height":3}}`},
+		{name: "pointer fields", v: struct {
+			S *plainShape
+			N int
+		}{&shape, 1}, expected: `{"S":{"width":2,"height":3},"N":1}`},
+		{name: "nil pointer field", v: struct{ S *plainShape }{}, expected: `{"S":null}`},
+		{name: "pointer to nil field", v: struct{ S *plainShape }{&nilShape}, expected: `{"S":null}`},
+		{name: "pointer field of pointer", v: &struct{ S *plainShape }{&shape}, expected: `{"S":{"width":2,"height":3}}`},
+		{name: "omitempty pointer field", v: struct {
+			S *plainShape `json:",omitempty"`
+		}{&shape}, expected: `{"S":{"width":2,"height":3}}`},
+		{name: "pointer field of empty interface", v: struct{ S *interface{} }{&empty}, expected: `{"S":1}`},
+		{name: "pointer", v: &shape, expected: `{"width":2,"height":3}`},
+		{name: "slice", v: []plainShape{shape, nil}, expected: `[{"width":2,"height":3},null]`},
+		{name: "slice of pointers", v: []*plainShape{&shape, nil}, expected: `[{"width":2,"height":3},null]`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bytes, err := json.Marshal(test.v)
+			assertErr(t, err)
+			assertEq(t, "interface", test.expected, string(bytes))
+		})
+	}
+}
+
+func TestMarshalUnion(t *testing.T) {
+	type shapes struct {
+		Shape  unionShape   `json:"shape"`
+		Shapes []unionShape `json:"shapes"`
+	}
+	v := shapes{
+		Shape:  &unionRect{Width: 2, Height: 3},
+		Shapes: []unionShape{unionCircle{Type: "circle", Radius: 1}, nil},
+	}
+	t.Run("Marshal", func(t *testing.T) {
+		bytes, err := json.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "union", `{"shape":{"type":"rect","width":2,"height":3},"shapes":[{"type":"circle","radius":1},null]}`, string(bytes))
+
+		var decoded shapes
+		assertErr(t, json.Unmarshal(bytes, &decoded))
+		assertEq(t, "round trip", fmt.Sprint(v.Shape, v.Shapes), fmt.Sprint(decoded.Shape, decoded.Shapes))
+	})
+	t.Run("MarshalIndent", func(t *testing.T) {
+		bytes, err := json.MarshalIndent(v, "", "  ")
+		assertErr(t, err)
+		assertEq(t, "union", `{
+  "shape": {
+    "type": "rect",
+    "width": 2,
+    "height": 3
+  },
+  "shapes": [
+    {
+      "type": "circle",
+      "radius": 1
+    },
+    null
+  ]
+}`, string(bytes))
+	})
+	t.Run("pointer to interface", func(t *testing.T) {
+		var shape unionShape = &unionRect{Width: 1, Height: 1}
+		bytes, err := json.Marshal(&shape)
+		assertErr(t, err)
+		assertEq(t, "union", `{"type":"rect","width":1,"height":1}`, string(bytes))
+		// the concrete value is encoded as is without the interface type
+		bytes, err = json.Marshal(shape)
+		assertErr(t, err)
+		assertEq(t, "concrete", `{"width":1,"height":1}`, string(bytes))
+	})
+	t.Run("pointer to interface field", func(t *testing.T) {
+		var shape unionShape = &unionRect{Width: 1, Height: 1}
+		bytes, err := json.Marshal(struct{ S *unionShape }{&shape})
+		assertErr(t, err)
+		assertEq(t, "union", `{"S":{"type":"rect","width":1,"height":1}}`, string(bytes))
+
+		var decoded struct{ S *unionShape }
+		assertErr(t, json.Unmarshal(bytes, &decoded))
+		assertEq(t, "round trip", fmt.Sprint(shape), fmt.Sprint(*decoded.S))
+
+		bytes, err = json.Marshal(struct{ S *unionShape }{})
+		assertErr(t, err)
+		assertEq(t, "nil", `{"S":null}`, string(bytes))
+	})
+	t.Run("no escape", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", " ")
+		assertErr(t, enc.Encode(struct{ S unionShape }{&unionRect{}}))
+		assertEq(t, "union", "{\n \"S\": {\n  \"type\": \"rect\",\n  \"width\": 0,\n  \"height\": 0\n }\n}\n", buf.String())
+	})
+	t.Run("debug", func(t *testing.T) {
+		bytes, err := json.MarshalWithOption(v, json.Debug())
+		assertErr(t, err)
+		assertEq(t, "union", `{"shape":{"type":"rect","width":2,"height":3},"shapes":[{"type":"circle","radius":1},null]}`, string(bytes))
+	})
+	t.Run("field for the key", func(t *testing.T) {
+		v := []unionShape{unionCircle{Radius: 1}, unionCircle{Type: "other", Radius: 2}}
+		bytes, err := json.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "union", `[{"type":"circle","radius":1},{"type":"circle","radius":2}]`, string(bytes))
+
+		var decoded []unionShape
+		assertErr(t, json.Unmarshal(bytes, &decoded))
+		assertEq(t, "round trip", "[{circle 1} {circle 2}]", fmt.Sprint(decoded))
+
+		bytes, err = json.MarshalIndent(v, "", " ")
+		assertErr(t, err)
+		assertEq(t, "indent", "[\n {\n  \"type\": \"circle\",\n  \"radius\": 1\n },\n {\n  \"type\": \"circle\",\n  \"radius\": 2\n }\n]", string(bytes))
+	})
+	t.Run("field for the key of embedded struct", func(t *testing.T) {
+		// the struct in the field isn't a value of the interface, so it is encoded with its own field for the key
+		v := []unionShape{unionGroup{
+			unionCircle: unionCircle{Type: "outer", Radius: 1},
+			Inner:       &unionGroup{unionCircle: unionCircle{Type: "inner", Radius: 2}},
+		}}
+		bytes, err := json.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "union", `[{"type":"group","radius":1,"inner":{"type":"inner","radius":2}}]`, string(bytes))
+
+		bytes, err = json.MarshalWithOption(v, json.Fields("radius"))
+		assertErr(t, err)
+		assertEq(t, "fields", `[{"type":"group","radius":1}]`, string(bytes))
+	})
+}
+
+type unionMarshalerShape struct{}
+
+func (unionMarshalerShape) Area() float64 { return 0 }
+
+func (unionMarshalerShape) MarshalJSON() ([]byte, error) { return []byte(`{}`), nil }
+
+type unionFloatShape float64
+
+func (s unionFloatShape) Area() float64 { return float64(s) }
+
+func TestRegisterUnionInvalidType(t *testing.T) {
+	for name, shape := range map[string]unionShape{
+		"marshaler":  unionMarshalerShape{},
+		"pointer":    &unionMarshalerShape{},
+		"non struct": unionFloatShape(0),
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if err := recover(); err == nil {
+					t.Fatal("expected panic")
+				}
+			}()
+			json.RegisterUnion((*unionShape)(nil), "type", map[string]unionShape{"shape": shape})
+		})
+	}
+}
@@ -22,6 +22,30 @@ type MarshalerError = errors.MarshalerError
 // A SyntaxError is a description of a JSON syntax error.
 type SyntaxError = errors.SyntaxError
 
+// An InvalidPathError describes a JSON Path expression that could not be parsed.
+type InvalidPathError = errors.InvalidPathError
+
+// An InvalidPointerError describes a JSON Pointer that could not be parsed.
+type InvalidPointerError = errors.InvalidPointerError
+
+// A PointerNotFoundError is returned when a JSON Pointer doesn't reference a value in the document.
+type PointerNotFoundError = errors.PointerNotFoundError
+
+// A DepthLimitError is returned when values are nested deeper than DecodeLimits.MaxDepth.
+type DepthLimitError = errors.DepthLimitError
+
+// A BytesLimitError is returned when the input is larger than DecodeLimits.MaxBytes.
+type BytesLimitError = errors.BytesLimitError
+
+// A StringLimitError is returned when a string is longer than DecodeLimits.MaxStringBytes.
+type StringLimitError = errors.StringLimitError
+
+// An ElementsLimitError is returned when an array has more elements than DecodeLimits.MaxElements.
+type ElementsLimitError = errors.ElementsLimitError
+
+// A KeysLimitError is returned when an object has more keys than DecodeLimits.MaxKeys.
+type KeysLimitError = errors.KeysLimitError
+
 // An UnmarshalFieldError describes a JSON object key that
 // led to an unexported (and therefore unwritable) struct field.
 //
@@ -30,8 +54,24 @@ type UnmarshalFieldError = errors.UnmarshalFieldError
 
 // An UnmarshalTypeError describes a JSON value that was
 // not appropriate for a value of a specific Go type.
+// In addition to the fields of encoding/json, it reports the path of the value,
+// so a composite literal of it must name its fields.
 type UnmarshalTypeError = errors.UnmarshalTypeError
 
+// A MissingFieldError is returned when an object doesn't have the keys of
+// the struct fields that have the "required" option.
+// Keys lists all the absent keys of the object.
+type MissingFieldError = errors.MissingFieldError
+
+// DecodeErrors is the list of the errors collected by decoding with the CollectErrors option.
+// Each error is either *UnmarshalTypeError for a value that was skipped or *MissingFieldError
+// for an object without required fields, and reports the offset and the path of the value.
+type DecodeErrors = errors.DecodeErrors
+
+// A PathElement is an object member or an array element on the path to a JSON value.
+// The path is reported by UnmarshalTypeError, SyntaxError and MissingFieldError.
+type PathElement = errors.PathElement
+
 // An UnsupportedTypeError is returned by Marshal when attempting
 // to encode an unsupported value type.
 type UnsupportedTypeError = errors.UnsupportedTypeError
@@ -43,7 +83,59 @@ var (
 	errNotAtBeginningOfValue = errors.ErrNotAtBeginningOfValue
 	errUnexpectedEndOfJSON   = errors.ErrUnexpectedEndOfJSON
 	errExpected              = errors.ErrExpected
+	errInvalidPath           = errors.ErrInvalidPath
+	errInvalidPointer        = errors.ErrInvalidPointer
+	errPointerNotFound       = errors.ErrPointerNotFound
 	errInvalidCharacter      = errors.ErrInvalidCharacter
 	errSyntax                = errors.ErrSyntax
 	errMarshaler             = errors.ErrMarshaler
+	prependErrorPath         = errors.PrependPath
+	prependErrorPathElements = errors.PrependPathElements
+	trimErrorPathRoot        = errors.TrimPathRoot
+	finishErrorPath          = errors.FinishPath
+	errorOffset              = errors.Offset
 )
+
+// errorSource is the part of the input that is still available to locate the offset of an error.
+type errorSource = errors.Source
+
+// errorPosition is the location of the offset of an error in the input.
+type errorPosition = errors.Position
+
+// locateError returns the position of the offset of err in src.
+func locateError(src errorSource, err error) (errorPosition, bool) {
+	offset, ok := errorOffset(err)
+	if !ok {
+		return errorPosition{}, false
+	}
+	return src.Locate(offset)
+}
+
+// ErrorPosition returns the 1-based line and column in bytes of the offset of err in data,
+// which is the input of Unmarshal, Path.Unmarshal or Pointer.Unmarshal that returned err.
+// The position is computed from data only when ErrorPosition is called.
+// err must be *SyntaxError, *UnmarshalTypeError or *MissingFieldError ( e.g. an element of DecodeErrors ).
+// Otherwise, ErrorPosition returns 0 for both.
+// Use Decoder.ErrorPosition for the errors returned by Decoder.
+func ErrorPosition(data []byte, err error) (line, column int) {
+	pos, ok := locateError(errorSource{Buf: data, Line: 1}, err)
+	if !ok {
+		return 0, 0
+	}
+	return pos.Line, pos.Column
+}
+
+// ErrorSource returns the line of data that caused err followed by a line
+// that has a caret under the column of the error, like
+//
+//	"price": 1x,
+//	          ^
+//
+// data and err are the same as ErrorPosition. If the position of err is unknown, ErrorSource returns an empty string.
+func ErrorSource(data []byte, err error) string {
+	pos, ok := locateError(errorSource{Buf: data, Line: 1}, err)
+	if !ok {
+		return ""
+	}
+	return pos.Format()
+}
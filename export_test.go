@@ -4,3 +4,8 @@ var (
 	NewSyntaxError    = errSyntax
 	NewMarshalerError = errMarshaler
 )
+
+// PrependErrorPath adds elem to the beginning of the path of err.
+func PrependErrorPath(err error, elem PathElement) error {
+	return finishErrorPath(prependErrorPath(err, elem))
+}
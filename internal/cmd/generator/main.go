@@ -90,6 +90,12 @@ func (t OpType) CodeType() CodeType {
 }
 
 func (t OpType) HeadToPtrHead() OpType {
+  switch t {
+  case OpStructHeadOmitZero:
+    return OpStructPtrHeadOmitZero
+  case OpStructHeadInline:
+    return OpStructPtrHeadInline
+  }
   if strings.Index(t.String(), "PtrHead") > 0 {
     return t
   }
@@ -125,6 +131,12 @@ func (t OpType) HeadToStringTagHead() OpType {
 }
 
 func (t OpType) PtrHeadToHead() OpType {
+  switch t {
+  case OpStructPtrHeadOmitZero:
+    return OpStructHeadOmitZero
+  case OpStructPtrHeadInline:
+    return OpStructHeadInline
+  }
   idx := strings.Index(t.String(), "Ptr")
   if idx == -1 {
     return t
@@ -269,6 +281,37 @@ func (t OpType) FieldToStringTagField() OpType {
 			})
 		}
 	}
+	// omitzero has only the operations for any type,
+	// because the zero value is checked by Opcode.IsZero instead of the type of the operation.
+	opTypes = append(opTypes,
+		createOpType("StructHeadOmitZero", "StructField"),
+		createOpType("StructPtrHeadOmitZero", "StructField"),
+		createOpType("StructFieldOmitZero", "StructField"),
+	)
+	// inline has only the operations for any map,
+	// because the entries of the map are encoded by the operations compiled for the type of the map.
+	opTypes = append(opTypes,
+		createOpType("StructHeadInline", "StructField"),
+		createOpType("StructPtrHeadInline", "StructField"),
+		createOpType("StructFieldInline", "StructField"),
+	)
+	// the string option with omitempty or omitzero has only the operations for the values quoted by the option,
+	// because the field is omitted by the operations of omitzero before them.
+	// IntString and UintString are created from primitiveTypes.
+	for _, typ := range []string{"Float32", "Float64", "String", "Bool", "Number"} {
+		opTypes = append(opTypes, createOpType(typ+"String", "Op"))
+	}
+	for _, typ := range []string{"Int", "Uint", "Float32", "Float64", "String", "Bool", "Number"} {
+		opTypes = append(opTypes, createOpType(typ+"PtrString", "Op"))
+	}
+	// UnionKey opens the object of a concrete type of a union and writes the key of the union before the fields.
+	opTypes = append(opTypes, createOpType("UnionKey", "Op"))
+	// MapSlice encodes the items in the order of the slice,
+	// and the value of each item is encoded by the operations compiled for its dynamic type like interface{}.
+	opTypes = append(opTypes,
+		createOpType("MapSlice", "Op"),
+		createOpType("MapSlicePtr", "Op"),
+	)
 	var b bytes.Buffer
 	if err := tmpl.Execute(&b, struct {
 		CodeTypes []string
@@ -1,6 +1,7 @@
 package encoder
 
 import (
+	"context"
 	"encoding"
 	"encoding/json"
 	"fmt"
@@ -14,14 +15,21 @@ import (
 )
 
 var (
-	marshalJSONType  = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
-	marshalTextType  = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
-	jsonNumberType   = reflect.TypeOf(json.Number(""))
-	cachedOpcodeSets []*OpcodeSet
-	cachedOpcodeMap  unsafe.Pointer // map[uintptr]*OpcodeSet
-	typeAddr         *runtime.TypeAddr
+	marshalJSONType        = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	marshalJSONContextType = reflect.TypeOf((*marshalerContext)(nil)).Elem()
+	marshalTextType        = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	jsonNumberType         = reflect.TypeOf(json.Number(""))
+	mapSliceType           = reflect.TypeOf(runtime.MapSlice(nil))
+	cachedOpcodeSets       []*OpcodeSet
+	cachedOpcodeMap        unsafe.Pointer      // map[uintptr]*OpcodeSet
+	cachedConfigOpcodeSets runtime.ConfigCache // configOpcodeSetKey => *OpcodeSet for the configurations without Encoders
+	typeAddr               *runtime.TypeAddr
 )
 
+type marshalerContext interface {
+	MarshalJSON(context.Context) ([]byte, error)
+}
+
 func init() {
 	typeAddr = runtime.AnalyzeTypeAddr()
 	if typeAddr == nil {
@@ -73,9 +81,101 @@ func compileToGetCodeSetSlowPath(typeptr uintptr) (*OpcodeSet, error) {
 	return codeSet, nil
 }
 
+// CompileConfig is the configuration of the compiler that can be changed for each encoding.
+// The zero value is the default configuration.
+type CompileConfig struct {
+	Fields   *FieldQuery             // selected fields. nil selects all fields
+	Naming   *runtime.NamingStrategy // naming strategy for the fields without a key in the tag
+	Encoders *TypeEncoders           // encoders registered for types in addition to the global ones
+	TagKey   string                  // key of the struct tag to read. empty reads the json key
+	Union    *runtime.Union          // union of the interface that has the value. the concrete types of it are encoded with the key
+}
+
+// unionOf returns the union of config if typ is one of the concrete types of it.
+func (c *CompileConfig) unionOf(typ *runtime.Type) *runtime.Union {
+	if c == nil || c.Union == nil {
+		return nil
+	}
+	if _, exists := c.Union.Names[typ]; !exists {
+		return nil
+	}
+	return c.Union
+}
+
+type configOpcodeSetKey struct {
+	typeptr uintptr
+	fields  string
+	naming  *runtime.NamingStrategy
+	tagKey  string
+	union   *runtime.Union
+	global  *TypeEncoders // the global encoders the opcodes are compiled with
+}
+
+// CompileToGetCodeSetWithConfig returns the OpcodeSet compiled with config.
+// The OpcodeSet is cached for each combination of type and configuration.
+// The OpcodeSets compiled with Encoders are cached by the Encoders, so that they are released with it.
+func CompileToGetCodeSetWithConfig(typeptr uintptr, config *CompileConfig) (*OpcodeSet, error) {
+	// noescape trick for header.typ ( reflect.*rtype )
+	copiedType := *(**runtime.Type)(unsafe.Pointer(&typeptr))
+
+	union := config.unionOf(copiedType)
+	if config == nil || (*config == CompileConfig{Union: config.Union} && union == nil) {
+		return CompileToGetCodeSet(typeptr)
+	}
+	cache := &cachedConfigOpcodeSets
+	if config.Encoders != nil {
+		cache = &config.Encoders.opcodeSets
+	}
+	key := configOpcodeSetKey{
+		typeptr: typeptr,
+		naming:  config.Naming,
+		tagKey:  config.TagKey,
+		union:   union,
+		global:  loadGlobalTypeEncoders(),
+	}
+	if config.Fields != nil {
+		key.fields = config.Fields.hash
+	}
+	if codeSet, exists := cache.Load(key); exists {
+		return codeSet.(*OpcodeSet), nil
+	}
+
+	ctx := &compileContext{
+		typ:                      copiedType,
+		structTypeToCompiledCode: map[uintptr]*CompiledCode{},
+		fieldsToCompiledCode:     map[selectedStructKey]*CompiledCode{},
+		fields:                   config.Fields,
+		naming:                   config.Naming,
+		encoders:                 config.Encoders,
+		tagKey:                   config.TagKey,
+	}
+	if union != nil {
+		ctx.unionKey = union.Key
+	}
+	code, err := compileHead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if union != nil && code.Op.CodeType() == CodeStructField {
+		// the type encoded by a function of RegisterTypeEncoder isn't a struct
+		code = newUnionKeyCode(code, union.Key, union.Names[copiedType])
+	}
+	code = copyOpcode(code)
+	codeLength := code.TotalLength()
+	codeSet := &OpcodeSet{
+		Type:       copiedType,
+		Code:       code,
+		CodeLength: codeLength,
+	}
+	cache.Store(key, codeSet)
+	return codeSet, nil
+}
+
 func compileHead(ctx *compileContext) (*Opcode, error) {
 	typ := ctx.typ
 	switch {
+	case ctx.typeEncoder(typ) != nil:
+		return compileTypeEncoder(ctx)
 	case implementsMarshalJSON(typ):
 		return compileMarshalJSON(ctx)
 	case implementsMarshalText(typ):
@@ -89,10 +189,14 @@ func compileHead(ctx *compileContext) (*Opcode, error) {
 		isPtr = true
 	}
 	switch {
+	case ctx.typeEncoder(typ) != nil:
+		return compileTypeEncoder(ctx.withType(typ))
 	case implementsMarshalJSON(typ):
 		return compileMarshalJSON(ctx)
 	case implementsMarshalText(typ):
 		return compileMarshalText(ctx)
+	case typ == runtime.Type2RType(mapSliceType):
+		return compileMapSlice(ctx.withType(typ))
 	}
 	switch typ.Kind() {
 	case reflect.Slice:
@@ -100,7 +204,7 @@ func compileHead(ctx *compileContext) (*Opcode, error) {
 		elem := typ.Elem()
 		if elem.Kind() == reflect.Uint8 {
 			p := runtime.PtrTo(elem)
-			if !p.Implements(marshalJSONType) && !p.Implements(marshalTextType) {
+			if !implementsMarshalJSONType(p) && !p.Implements(marshalTextType) {
 				if isPtr {
 					return compileBytesPtr(ctx)
 				}
@@ -132,6 +236,7 @@ func compileHead(ctx *compileContext) (*Opcode, error) {
 		}
 		optimizeStructEnd(code)
 		linkRecursiveCode(code)
+		optimizeDirectMapSlicePtr(code)
 		return code, nil
 	case reflect.Int:
 		ctx := ctx.withType(typ)
@@ -237,6 +342,16 @@ func compileHead(ctx *compileContext) (*Opcode, error) {
 	}
 }
 
+// optimizeDirectMapSlicePtr fixes the *MapSlice field of a struct that has only the field.
+// The value of such a struct is the pointer of the field itself,
+// so the operation of the field receives the pointer instead of the address of the field.
+func optimizeDirectMapSlicePtr(head *Opcode) {
+	if head.Indirect || head.Next.Op != OpMapSlicePtr || head.Next.PtrNum == 0 {
+		return
+	}
+	head.Next.PtrNum--
+}
+
 func linkRecursiveCode(c *Opcode) {
 	for code := c; code.Op != OpEnd && code.Op != OpRecursiveEnd; {
 		switch code.Op {
@@ -327,15 +442,19 @@ func optimizeStructEnd(c *Opcode) {
 	}
 }
 
+func implementsMarshalJSONType(typ *runtime.Type) bool {
+	return typ.Implements(marshalJSONType) || typ.Implements(marshalJSONContextType)
+}
+
 func implementsMarshalJSON(typ *runtime.Type) bool {
-	if !typ.Implements(marshalJSONType) {
+	if !implementsMarshalJSONType(typ) {
 		return false
 	}
 	if typ.Kind() != reflect.Ptr {
 		return true
 	}
 	// type kind is reflect.Ptr
-	if !typ.Elem().Implements(marshalJSONType) {
+	if !implementsMarshalJSONType(typ.Elem()) {
 		return true
 	}
 	// needs to dereference
@@ -360,10 +479,14 @@ func implementsMarshalText(typ *runtime.Type) bool {
 func compile(ctx *compileContext, isPtr bool) (*Opcode, error) {
 	typ := ctx.typ
 	switch {
+	case ctx.typeEncoder(typ) != nil:
+		return compileTypeEncoder(ctx)
 	case implementsMarshalJSON(typ):
 		return compileMarshalJSON(ctx)
 	case implementsMarshalText(typ):
 		return compileMarshalText(ctx)
+	case typ == runtime.Type2RType(mapSliceType):
+		return compileMapSlice(ctx)
 	}
 	switch typ.Kind() {
 	case reflect.Ptr:
@@ -372,7 +495,7 @@ func compile(ctx *compileContext, isPtr bool) (*Opcode, error) {
 		elem := typ.Elem()
 		if elem.Kind() == reflect.Uint8 {
 			p := runtime.PtrTo(elem)
-			if !p.Implements(marshalJSONType) && !p.Implements(marshalTextType) {
+			if !implementsMarshalJSONType(p) && !p.Implements(marshalTextType) {
 				return compileBytes(ctx)
 			}
 		}
@@ -457,6 +580,8 @@ func convertPtrOp(code *Opcode) OpType {
 		return OpMarshalTextPtr
 	case OpInterface:
 		return OpInterfacePtr
+	case OpMapSlice:
+		return OpMapSlicePtr
 	case OpRecursive:
 		return OpRecursivePtr
 	}
@@ -515,9 +640,12 @@ func compilePtr(ctx *compileContext) (*Opcode, error) {
 }
 
 func compileMarshalJSON(ctx *compileContext) (*Opcode, error) {
-	code := newOpCode(ctx, OpMarshalJSON)
 	typ := ctx.typ
-	if !typ.Implements(marshalJSONType) && runtime.PtrTo(typ).Implements(marshalJSONType) {
+	if ctx.typeEncoder(typ) != nil {
+		return compileTypeEncoder(ctx)
+	}
+	code := newOpCode(ctx, OpMarshalJSON)
+	if !implementsMarshalJSONType(typ) && implementsMarshalJSONType(runtime.PtrTo(typ)) {
 		code.AddrForMarshaler = true
 	}
 	code.IsNilableType = isNilableType(typ)
@@ -525,6 +653,45 @@ func compileMarshalJSON(ctx *compileContext) (*Opcode, error) {
 	return code, nil
 }
 
+func compileTypeEncoder(ctx *compileContext) (*Opcode, error) {
+	code := newOpCode(ctx, OpMarshalJSON)
+	code.TypeEncoder = ctx.typeEncoder(ctx.typ)
+	code.IsNilableType = isNilableType(ctx.typ)
+	ctx.incIndex()
+	return code, nil
+}
+
+// stringTagValueOps maps the operations of the values quoted by the string option
+// to the operations that quote them.
+var stringTagValueOps = map[OpType]OpType{
+	OpInt:        OpIntString,
+	OpIntPtr:     OpIntPtrString,
+	OpUint:       OpUintString,
+	OpUintPtr:    OpUintPtrString,
+	OpFloat32:    OpFloat32String,
+	OpFloat32Ptr: OpFloat32PtrString,
+	OpFloat64:    OpFloat64String,
+	OpFloat64Ptr: OpFloat64PtrString,
+	OpString:     OpStringString,
+	OpStringPtr:  OpStringPtrString,
+	OpBool:       OpBoolString,
+	OpBoolPtr:    OpBoolPtrString,
+	OpNumber:     OpNumberString,
+	OpNumberPtr:  OpNumberPtrString,
+}
+
+// isStringTagValueOp reports whether the string option quotes the value encoded by op.
+func isStringTagValueOp(op OpType) bool {
+	_, exists := stringTagValueOps[op]
+	return exists
+}
+
+// convertStringTagOmitValue converts the value code of a field that has the string option and omitempty or omitzero
+// into the code that quotes the value, because the operations of omitzero that omit the field don't quote it.
+func convertStringTagOmitValue(code *Opcode) {
+	code.Op = stringTagValueOps[code.Op]
+}
+
 func compileMarshalText(ctx *compileContext) (*Opcode, error) {
 	code := newOpCode(ctx, OpMarshalText)
 	typ := ctx.typ
@@ -868,6 +1035,12 @@ func compileInterfacePtr(ctx *compileContext) (*Opcode, error) {
 	return code, nil
 }
 
+func compileMapSlice(ctx *compileContext) (*Opcode, error) {
+	code := newMapSliceCode(ctx)
+	ctx.incIndex()
+	return code, nil
+}
+
 func compileSlice(ctx *compileContext) (*Opcode, error) {
 	elem := ctx.typ.Elem()
 	size := elem.Size()
@@ -903,7 +1076,7 @@ func compileSlice(ctx *compileContext) (*Opcode, error) {
 func compileListElem(ctx *compileContext) (*Opcode, error) {
 	typ := ctx.typ
 	switch {
-	case !typ.Implements(marshalJSONType) && runtime.PtrTo(typ).Implements(marshalJSONType):
+	case !implementsMarshalJSONType(typ) && implementsMarshalJSONType(runtime.PtrTo(typ)):
 		return compileMarshalJSON(ctx)
 	case !typ.Implements(marshalTextType) && runtime.PtrTo(typ).Implements(marshalTextType):
 		return compileMarshalText(ctx)
@@ -964,7 +1137,7 @@ func compileMap(ctx *compileContext) (*Opcode, error) {
 
 	typ := ctx.typ
 	keyType := ctx.typ.Key()
-	keyCode, err := compileKey(ctx.withType(keyType))
+	keyCode, err := compileKey(ctx.withType(keyType).withFields(nil))
 	if err != nil {
 		return nil, err
 	}
@@ -972,7 +1145,12 @@ func compileMap(ctx *compileContext) (*Opcode, error) {
 	value := newMapValueCode(ctx, header)
 	ctx.incIndex()
 
-	valueCode, err := compileMapValue(ctx.withType(typ.Elem()))
+	// map keys can be selected only if the key is a string
+	fields := ctx.fields
+	if keyType.Kind() != reflect.String {
+		fields = nil
+	}
+	valueCode, err := compileMapValue(ctx.withType(typ.Elem()).withFields(fields.Elem()))
 	if err != nil {
 		return nil, err
 	}
@@ -983,6 +1161,9 @@ func compileMap(ctx *compileContext) (*Opcode, error) {
 
 	ctx = ctx.decIndent()
 
+	header.Fields = fields
+	key.Fields = fields
+
 	header.MapKey = key
 	header.MapValue = value
 
@@ -1018,10 +1199,24 @@ func compileMapValue(ctx *compileContext) (*Opcode, error) {
 	}
 }
 
+func isOmitZeroField(code *Opcode, tag *runtime.StructTag) bool {
+	if tag.IsOmitEmpty && (code.Op == OpMapSlice || code.Op == OpMapSlicePtr) {
+		// MapSlice has no operations of omitempty, so the empty value is checked by IsZeroFunc
+		return true
+	}
+	return tag.IsOmitZero && !isOmitZeroWithOmitEmptyCode(code, runtime.Type2RType(tag.Field.Type))
+}
+
 func optimizeStructHeader(code *Opcode, tag *runtime.StructTag) OpType {
+	if tag.IsInline {
+		return OpStructHeadInline
+	}
+	if isOmitZeroField(code, tag) {
+		return OpStructHeadOmitZero
+	}
 	headType := code.ToHeaderType()
 	switch {
-	case tag.IsOmitEmpty:
+	case tag.IsOmitEmpty, tag.IsOmitZero:
 		headType = headType.HeadToOmitEmptyHead()
 	case tag.IsString:
 		headType = headType.HeadToStringTagHead()
@@ -1030,9 +1225,15 @@ func optimizeStructHeader(code *Opcode, tag *runtime.StructTag) OpType {
 }
 
 func optimizeStructField(code *Opcode, tag *runtime.StructTag) OpType {
+	if tag.IsInline {
+		return OpStructFieldInline
+	}
+	if isOmitZeroField(code, tag) {
+		return OpStructFieldOmitZero
+	}
 	fieldType := code.ToFieldType()
 	switch {
-	case tag.IsOmitEmpty:
+	case tag.IsOmitEmpty, tag.IsOmitZero:
 		fieldType = fieldType.FieldToOmitEmptyField()
 	case tag.IsString:
 		fieldType = fieldType.FieldToStringTagField()
@@ -1047,14 +1248,48 @@ func recursiveCode(ctx *compileContext, jmp *CompiledCode) *Opcode {
 }
 
 func compiledCode(ctx *compileContext) *Opcode {
+	if ctx.unionKey != "" {
+		// the struct of a union is compiled without the field for the key, so it isn't shared with the other references
+		return nil
+	}
 	typ := ctx.typ
 	typeptr := uintptr(unsafe.Pointer(typ))
+	if ctx.fields != nil {
+		if cc, exists := ctx.fieldsToCompiledCode[selectedStructKey{typeptr: typeptr, fields: ctx.fields}]; exists {
+			return recursiveCode(ctx, cc)
+		}
+		return nil
+	}
 	if cc, exists := ctx.structTypeToCompiledCode[typeptr]; exists {
 		return recursiveCode(ctx, cc)
 	}
 	return nil
 }
 
+func setCompiledCode(ctx *compileContext, compiled *CompiledCode) {
+	if ctx.unionKey != "" {
+		return
+	}
+	typeptr := uintptr(unsafe.Pointer(ctx.typ))
+	if ctx.fields != nil {
+		ctx.fieldsToCompiledCode[selectedStructKey{typeptr: typeptr, fields: ctx.fields}] = compiled
+		return
+	}
+	ctx.structTypeToCompiledCode[typeptr] = compiled
+}
+
+func deleteCompiledCode(ctx *compileContext) {
+	if ctx.unionKey != "" {
+		return
+	}
+	typeptr := uintptr(unsafe.Pointer(ctx.typ))
+	if ctx.fields != nil {
+		delete(ctx.fieldsToCompiledCode, selectedStructKey{typeptr: typeptr, fields: ctx.fields})
+		return
+	}
+	delete(ctx.structTypeToCompiledCode, typeptr)
+}
+
 func structHeader(ctx *compileContext, fieldCode *Opcode, valueCode *Opcode, tag *runtime.StructTag) *Opcode {
 	fieldCode.Indent--
 	op := optimizeStructHeader(valueCode, tag)
@@ -1062,6 +1297,10 @@ func structHeader(ctx *compileContext, fieldCode *Opcode, valueCode *Opcode, tag
 	fieldCode.Mask = valueCode.Mask
 	fieldCode.RshiftNum = valueCode.RshiftNum
 	fieldCode.PtrNum = valueCode.PtrNum
+	fieldCode.TypeEncoder = valueCode.TypeEncoder
+	if op == OpStructHeadOmitZero {
+		fieldCode.IsZero = newIsZeroFunc(runtime.Type2RType(tag.Field.Type), tag.IsOmitEmpty)
+	}
 	if op.IsMultipleOpHead() {
 		return valueCode.BeforeLastCode()
 	}
@@ -1075,6 +1314,10 @@ func structField(ctx *compileContext, fieldCode *Opcode, valueCode *Opcode, tag
 	fieldCode.PtrNum = valueCode.PtrNum
 	fieldCode.Mask = valueCode.Mask
 	fieldCode.RshiftNum = valueCode.RshiftNum
+	fieldCode.TypeEncoder = valueCode.TypeEncoder
+	if op == OpStructFieldOmitZero {
+		fieldCode.IsZero = newIsZeroFunc(runtime.Type2RType(tag.Field.Type), tag.IsOmitEmpty)
+	}
 	if op.IsMultipleOpField() {
 		return valueCode.BeforeLastCode()
 	}
@@ -1303,10 +1546,10 @@ func compileStruct(ctx *compileContext, isPtr bool) (*Opcode, error) {
 	if code := compiledCode(ctx); code != nil {
 		return code, nil
 	}
+	fields := ctx.fields
 	typ := ctx.typ
-	typeptr := uintptr(unsafe.Pointer(typ))
 	compiled := &CompiledCode{}
-	ctx.structTypeToCompiledCode[typeptr] = compiled
+	setCompiledCode(ctx, compiled)
 	// header => code => structField => code => end
 	//                        ^          |
 	//                        |__________|
@@ -1324,17 +1567,43 @@ func compileStruct(ctx *compileContext, isPtr bool) (*Opcode, error) {
 	anonymousFields := map[string][]structFieldPair{}
 	for i := 0; i < fieldNum; i++ {
 		field := typ.Field(i)
-		if runtime.IsIgnoredStructField(field) {
+		if runtime.IsIgnoredStructField(field, ctx.tagKey) {
 			continue
 		}
-		tags = append(tags, runtime.StructTagFromField(field))
+		tags = append(tags, runtime.StructTagFromField(field, ctx.tagKey, ctx.naming))
 	}
+	tags = moveInlineFieldsToEnd(tags)
+	embedding := append(ctx.embedding[:len(ctx.embedding):len(ctx.embedding)], typ)
 	for i, tag := range tags {
 		field := tag.Field
 		fieldType := runtime.Type2RType(field.Type)
+		isPromoted := isPromotedField(tag, fieldType)
+		if isPromoted && ctx.isEmbedding(promotedStructType(fieldType)) {
+			// the fields of a recursively embedded struct are hidden by the same fields at the shallower depth
+			continue
+		}
+		if ctx.unionKey != "" && tag.Key == ctx.unionKey {
+			// the key of the union is written by OpUnionKey before the fields
+			continue
+		}
+		fieldFields := fields
+		if fields != nil && !isPromoted {
+			selected, exists := fields.Field(tag.Key)
+			if !exists {
+				continue
+			}
+			fieldFields = selected
+		}
 		fieldOpcodeIndex := ctx.opcodeIndex
 		fieldPtrIndex := ctx.ptrIndex
 		ctx.incIndex()
+		fieldEmbedding := embedding
+		fieldUnionKey := ctx.unionKey
+		if !isPromoted {
+			fieldEmbedding = nil
+			fieldUnionKey = ""
+		}
+		fieldCtx := ctx.withType(fieldType).withFields(fieldFields).withEmbedding(fieldEmbedding).withUnionKey(fieldUnionKey)
 
 		nilcheck := true
 		addrForMarshaler := false
@@ -1347,7 +1616,7 @@ func compileStruct(ctx *compileContext, isPtr bool) (*Opcode, error) {
 			// *struct{ field T } => struct { field *T }
 			// func (*T) MarshalJSON() ([]byte, error)
 			// move pointer position from head to first field
-			code, err := compileMarshalJSON(ctx.withType(fieldType))
+			code, err := compileMarshalJSON(fieldCtx)
 			if err != nil {
 				return nil, err
 			}
@@ -1360,7 +1629,7 @@ func compileStruct(ctx *compileContext, isPtr bool) (*Opcode, error) {
 			// *struct{ field T } => struct { field *T }
 			// func (*T) MarshalText() ([]byte, error)
 			// move pointer position from head to first field
-			code, err := compileMarshalText(ctx.withType(fieldType))
+			code, err := compileMarshalText(fieldCtx)
 			if err != nil {
 				return nil, err
 			}
@@ -1372,7 +1641,7 @@ func compileStruct(ctx *compileContext, isPtr bool) (*Opcode, error) {
 		case isPtr && isPtrMarshalJSONType(fieldType):
 			// *struct{ field T }
 			// func (*T) MarshalJSON() ([]byte, error)
-			code, err := compileMarshalJSON(ctx.withType(fieldType))
+			code, err := compileMarshalJSON(fieldCtx)
 			if err != nil {
 				return nil, err
 			}
@@ -1382,7 +1651,7 @@ func compileStruct(ctx *compileContext, isPtr bool) (*Opcode, error) {
 		case isPtr && isPtrMarshalTextType(fieldType):
 			// *struct{ field T }
 			// func (*T) MarshalText() ([]byte, error)
-			code, err := compileMarshalText(ctx.withType(fieldType))
+			code, err := compileMarshalText(fieldCtx)
 			if err != nil {
 				return nil, err
 			}
@@ -1390,12 +1659,21 @@ func compileStruct(ctx *compileContext, isPtr bool) (*Opcode, error) {
 			nilcheck = false
 			valueCode = code
 		default:
-			code, err := compile(ctx.withType(fieldType), isPtr)
+			code, err := compile(fieldCtx, isPtr)
 			if err != nil {
 				return nil, err
 			}
 			valueCode = code
 		}
+		opTag := tag
+		if tag.IsString && (tag.IsOmitEmpty || tag.IsOmitZero) && isStringTagValueOp(valueCode.Op) {
+			convertStringTagOmitValue(valueCode)
+			// the field is omitted by IsZeroFunc, which checks the empty values for omitempty as well
+			omitZeroTag := *tag
+			omitZeroTag.IsOmitZero = true
+			omitZeroTag.IsString = false
+			opTag = &omitZeroTag
+		}
 
 		if field.Anonymous {
 			tagKey := ""
@@ -1442,15 +1720,18 @@ func compileStruct(ctx *compileContext, isPtr bool) (*Opcode, error) {
 			IsNextOpPtrType:  strings.Contains(valueCode.Op.String(), "Ptr"),
 			IsNilableType:    isNilableType,
 		}
+		if tag.IsInline {
+			fieldCode.Config = fieldCtx.withFields(fieldFields.ExcludeMapKeys(structKeys(ctx, embedding))).compileConfig()
+		}
 		if fieldIdx == 0 {
 			fieldCode.HeadIdx = fieldCode.Idx
-			code = structHeader(ctx, fieldCode, valueCode, tag)
+			code = structHeader(ctx, fieldCode, valueCode, opTag)
 			head = fieldCode
 			prevField = fieldCode
 		} else {
 			fieldCode.HeadIdx = head.HeadIdx
 			code.Next = fieldCode
-			code = structField(ctx, fieldCode, valueCode, tag)
+			code = structField(ctx, fieldCode, valueCode, opTag)
 			prevField.NextField = fieldCode
 			fieldCode.PrevField = prevField
 			prevField = fieldCode
@@ -1498,17 +1779,109 @@ func compileStruct(ctx *compileContext, isPtr bool) (*Opcode, error) {
 	ret := (*Opcode)(unsafe.Pointer(head))
 	compiled.Code = ret
 
-	delete(ctx.structTypeToCompiledCode, typeptr)
+	deleteCompiledCode(ctx)
 
 	if !disableIndirectConversion && !head.Indirect && isPtr {
 		head.Indirect = true
 	}
+	if !head.Indirect {
+		convertDirectHeadValue(head)
+	}
 
 	return ret, nil
 }
 
+// convertDirectHeadValue converts the value code of the head of a struct that is stored directly ( e.g. struct { field *T } ).
+// The head passes the value of the field instead of the address of it to the value code,
+// which is handled by the fused head operations but not by the codes that follow a generic head.
+func convertDirectHeadValue(head *Opcode) {
+	switch head.Next.Op {
+	case OpIntPtr, OpUintPtr, OpFloat32Ptr, OpFloat64Ptr, OpStringPtr, OpBoolPtr, OpBytesPtr, OpNumberPtr,
+		OpIntPtrString, OpUintPtrString, OpFloat32PtrString, OpFloat64PtrString, OpStringPtrString, OpBoolPtrString, OpNumberPtrString,
+		OpInterfacePtr, OpMapSlicePtr, OpSlicePtr, OpArrayPtr, OpMapPtr:
+		head.Next.PtrNum--
+	}
+	if head.Op == OpStructHeadOmitZero {
+		isZero := head.IsZero
+		head.IsZero = func(p unsafe.Pointer) bool {
+			return isZero(unsafe.Pointer(&p))
+		}
+	}
+}
+
+// moveInlineFieldsToEnd moves the fields that have the inline option after the other fields,
+// so that the entries of the inline maps are encoded after the known fields.
+func moveInlineFieldsToEnd(tags runtime.StructTags) runtime.StructTags {
+	sorted := make(runtime.StructTags, 0, len(tags))
+	for _, tag := range tags {
+		if !tag.IsInline {
+			sorted = append(sorted, tag)
+		}
+	}
+	for _, tag := range tags {
+		if tag.IsInline {
+			sorted = append(sorted, tag)
+		}
+	}
+	return sorted
+}
+
+// structKeys returns the keys the entries of an inline map must not have.
+// They are the keys of the fields of the struct and the structs it is promoted to ( embedding ), and the key of the union.
+func structKeys(ctx *compileContext, embedding []*runtime.Type) map[string]struct{} {
+	keys := map[string]struct{}{}
+	if ctx.unionKey != "" {
+		keys[ctx.unionKey] = struct{}{}
+	}
+	var visited []*runtime.Type
+	for _, t := range embedding {
+		visited = addStructKeys(ctx, t, keys, visited)
+	}
+	return keys
+}
+
+// addStructKeys adds the keys of the fields of typ including the promoted ones to keys.
+func addStructKeys(ctx *compileContext, typ *runtime.Type, keys map[string]struct{}, visited []*runtime.Type) []*runtime.Type {
+	for _, t := range visited {
+		if t == typ {
+			return visited
+		}
+	}
+	visited = append(visited, typ)
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if runtime.IsIgnoredStructField(field, ctx.tagKey) {
+			continue
+		}
+		tag := runtime.StructTagFromField(field, ctx.tagKey, ctx.naming)
+		fieldType := runtime.Type2RType(field.Type)
+		if isPromotedField(tag, fieldType) {
+			visited = addStructKeys(ctx, promotedStructType(fieldType), keys, visited)
+		} else if !tag.IsInline {
+			keys[tag.Key] = struct{}{}
+		}
+	}
+	return visited
+}
+
+// isPromotedField reports whether the fields of the embedded struct are promoted to the parent.
+func isPromotedField(tag *runtime.StructTag, typ *runtime.Type) bool {
+	if !tag.Field.Anonymous || tag.IsTaggedKey {
+		return false
+	}
+	return promotedStructType(typ).Kind() == reflect.Struct
+}
+
+// promotedStructType returns the struct type of an embedded field of typ.
+func promotedStructType(typ *runtime.Type) *runtime.Type {
+	if typ.Kind() == reflect.Ptr {
+		return typ.Elem()
+	}
+	return typ
+}
+
 func isPtrMarshalJSONType(typ *runtime.Type) bool {
-	return !typ.Implements(marshalJSONType) && runtime.PtrTo(typ).Implements(marshalJSONType)
+	return !implementsMarshalJSONType(typ) && implementsMarshalJSONType(runtime.PtrTo(typ))
 }
 
 func isPtrMarshalTextType(typ *runtime.Type) bool {
@@ -3,6 +3,7 @@
 package encoder
 
 import (
+	"sync/atomic"
 	"unsafe"
 
 	"github.com/goccy/go-json/internal/runtime"
@@ -37,3 +38,11 @@ func CompileToGetCodeSet(typeptr uintptr) (*OpcodeSet, error) {
 	cachedOpcodeSets[index] = codeSet
 	return codeSet, nil
 }
+
+// clearCachedOpcodeSets discards the opcodes compiled without a configuration.
+func clearCachedOpcodeSets() {
+	for i := range cachedOpcodeSets {
+		cachedOpcodeSets[i] = nil
+	}
+	atomic.StorePointer(&cachedOpcodeMap, nil)
+}
@@ -4,6 +4,7 @@ package encoder
 
 import (
 	"sync"
+	"sync/atomic"
 	"unsafe"
 
 	"github.com/goccy/go-json/internal/runtime"
@@ -45,3 +46,13 @@ func CompileToGetCodeSet(typeptr uintptr) (*OpcodeSet, error) {
 	setsMu.Unlock()
 	return codeSet, nil
 }
+
+// clearCachedOpcodeSets discards the opcodes compiled without a configuration.
+func clearCachedOpcodeSets() {
+	setsMu.Lock()
+	defer setsMu.Unlock()
+	for i := range cachedOpcodeSets {
+		cachedOpcodeSets[i] = nil
+	}
+	atomic.StorePointer(&cachedOpcodeMap, nil)
+}
@@ -10,10 +10,24 @@ type compileContext struct {
 	ptrIndex                 int
 	indent                   int
 	structTypeToCompiledCode map[uintptr]*CompiledCode
+	fieldsToCompiledCode     map[selectedStructKey]*CompiledCode // the structs compiled with selected fields
+	fields                   *FieldQuery
+	embedding                []*runtime.Type // the structs the fields of the current struct are promoted to
+	unionKey                 string          // key of the union written before the fields of the current struct
+	naming                   *runtime.NamingStrategy
+	encoders                 *TypeEncoders
+	tagKey                   string
 
 	parent *compileContext
 }
 
+// selectedStructKey is the key of a struct compiled with selected fields.
+// The query is passed to the promoted fields as it is, so the same query is found again in a recursive struct.
+type selectedStructKey struct {
+	typeptr uintptr
+	fields  *FieldQuery
+}
+
 func (c *compileContext) context() *compileContext {
 	return &compileContext{
 		typ:                      c.typ,
@@ -21,6 +35,13 @@ func (c *compileContext) context() *compileContext {
 		ptrIndex:                 c.ptrIndex,
 		indent:                   c.indent,
 		structTypeToCompiledCode: c.structTypeToCompiledCode,
+		fieldsToCompiledCode:     c.fieldsToCompiledCode,
+		fields:                   c.fields,
+		embedding:                c.embedding,
+		unionKey:                 c.unionKey,
+		naming:                   c.naming,
+		encoders:                 c.encoders,
+		tagKey:                   c.tagKey,
 		parent:                   c,
 	}
 }
@@ -31,6 +52,58 @@ func (c *compileContext) withType(typ *runtime.Type) *compileContext {
 	return ctx
 }
 
+func (c *compileContext) withFields(fields *FieldQuery) *compileContext {
+	ctx := c.context()
+	ctx.fields = fields
+	return ctx
+}
+
+// withEmbedding returns the context to compile a field of a struct that has the embedding structs.
+// The embedding structs are kept only for the promoted fields.
+func (c *compileContext) withEmbedding(embedding []*runtime.Type) *compileContext {
+	ctx := c.context()
+	ctx.embedding = embedding
+	return ctx
+}
+
+// isEmbedding reports whether typ is the current struct or one of the structs its fields are promoted to.
+func (c *compileContext) isEmbedding(typ *runtime.Type) bool {
+	if c.typ == typ {
+		return true
+	}
+	for _, t := range c.embedding {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// withUnionKey returns the context to compile a field of a struct that is encoded with the key of a union.
+// The key is kept only for the promoted fields, which are encoded into the same object.
+func (c *compileContext) withUnionKey(key string) *compileContext {
+	ctx := c.context()
+	ctx.unionKey = key
+	return ctx
+}
+
+// compileConfig returns the configuration to compile a type at run time in the current context.
+func (c *compileContext) compileConfig() *CompileConfig {
+	if c.fields == nil && c.naming == nil && c.encoders == nil && c.tagKey == "" {
+		return nil
+	}
+	return &CompileConfig{Fields: c.fields, Naming: c.naming, Encoders: c.encoders, TagKey: c.tagKey}
+}
+
+// typeEncoder returns the encoder registered for typ.
+// The encoders given by the configuration take precedence over the globally registered ones.
+func (c *compileContext) typeEncoder(typ *runtime.Type) TypeEncoderFunc {
+	if fn := c.encoders.Lookup(typ); fn != nil {
+		return fn
+	}
+	return lookupGlobalTypeEncoder(typ)
+}
+
 func (c *compileContext) incIndent() *compileContext {
 	ctx := c.context()
 	ctx.indent++
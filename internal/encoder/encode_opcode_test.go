@@ -1,6 +1,8 @@
 package encoder
 
 import (
+	"reflect"
+	"strconv"
 	"testing"
 	"unsafe"
 )
@@ -16,3 +18,26 @@ func TestDumpOpcode(t *testing.T) {
 	}
 	codeSet.Code.Dump()
 }
+
+func appendTestInt(b []byte, v interface{}) ([]byte, error) {
+	return strconv.AppendInt(b, int64(v.(int)), 10), nil
+}
+
+func TestTypeEncodersWith(t *testing.T) {
+	typ := reflect.TypeOf(0)
+	var encoders *TypeEncoders
+	if encoders.With(typ, appendTestInt) != encoders.With(typ, appendTestInt) {
+		t.Fatal("expected the encoders for the same function to be shared")
+	}
+	derived := encoders.With(typ, appendTestInt)
+	if derived.With(reflect.TypeOf(""), appendTestInt) != derived.With(reflect.TypeOf(""), appendTestInt) {
+		t.Fatal("expected the derived encoders for the same function to be shared")
+	}
+	prefix := "x"
+	closure := func(b []byte, v interface{}) ([]byte, error) {
+		return append(append(b, prefix...), strconv.Itoa(v.(int))...), nil
+	}
+	if encoders.With(typ, closure) == derived {
+		t.Fatal("expected the encoders for another function not to be shared")
+	}
+}
@@ -2,6 +2,7 @@ package encoder
 
 import (
 	"bytes"
+	"context"
 	"encoding"
 	"encoding/base64"
 	"encoding/json"
@@ -39,6 +40,8 @@ func (t OpType) IsMultipleOpHead() bool {
 		return true
 	case OpStructHeadOmitEmpty:
 		return true
+	case OpStructHeadOmitZero:
+		return true
 	case OpStructHeadOmitEmptySlice:
 		return true
 	case OpStructHeadStringTagSlice:
@@ -91,6 +94,8 @@ func (t OpType) IsMultipleOpField() bool {
 		return true
 	case OpStructFieldOmitEmpty:
 		return true
+	case OpStructFieldOmitZero:
+		return true
 	case OpStructFieldOmitEmptySlice:
 		return true
 	case OpStructFieldStringTagSlice:
@@ -305,6 +310,7 @@ func MapIterNext(it unsafe.Pointer)
 func MapLen(m unsafe.Pointer) int
 
 type RuntimeContext struct {
+	Context    context.Context
 	Buf        []byte
 	Ptrs       []uintptr
 	KeepRefs   []unsafe.Pointer
@@ -413,7 +419,38 @@ func AppendNumber(b []byte, n json.Number) ([]byte, error) {
 	return b, nil
 }
 
-func AppendMarshalJSON(code *Opcode, b []byte, v interface{}, escape bool) ([]byte, error) {
+// callMarshalJSON calls MarshalJSON of json.Marshaler or marshalerContext.
+// if v implements neither of them, ok is false.
+func callMarshalJSON(ctx *RuntimeContext, v interface{}) (bb []byte, ok bool, err error) {
+	switch marshaler := v.(type) {
+	case marshalerContext:
+		c := ctx.Context
+		if c == nil {
+			c = context.Background()
+		}
+		bb, err = marshaler.MarshalJSON(c)
+		return bb, true, err
+	case json.Marshaler:
+		bb, err = marshaler.MarshalJSON()
+		return bb, true, err
+	}
+	return nil, false, nil
+}
+
+// appendTypeEncoder appends the result of the encoder registered for the type.
+// The result is trusted, so it is neither validated nor compacted.
+func appendTypeEncoder(code *Opcode, b []byte, v interface{}) ([]byte, error) {
+	bb, err := code.TypeEncoder(b, v)
+	if err != nil {
+		return nil, &errors.MarshalerError{Type: reflect.TypeOf(v), Err: err}
+	}
+	return bb, nil
+}
+
+func AppendMarshalJSON(ctx *RuntimeContext, code *Opcode, b []byte, v interface{}, escape bool) ([]byte, error) {
+	if code.TypeEncoder != nil {
+		return appendTypeEncoder(code, b, v)
+	}
 	rv := reflect.ValueOf(v) // convert by dynamic interface type
 	if code.AddrForMarshaler {
 		if rv.CanAddr() {
@@ -425,11 +462,10 @@ func AppendMarshalJSON(code *Opcode, b []byte, v interface{}, escape bool) ([]by
 		}
 	}
 	v = rv.Interface()
-	marshaler, ok := v.(json.Marshaler)
+	bb, ok, err := callMarshalJSON(ctx, v)
 	if !ok {
 		return AppendNull(b), nil
 	}
-	bb, err := marshaler.MarshalJSON()
 	if err != nil {
 		return nil, &errors.MarshalerError{Type: reflect.TypeOf(v), Err: err}
 	}
@@ -442,33 +478,42 @@ func AppendMarshalJSON(code *Opcode, b []byte, v interface{}, escape bool) ([]by
 }
 
 func AppendMarshalJSONIndent(ctx *RuntimeContext, code *Opcode, b []byte, v interface{}, indent int, escape bool) ([]byte, error) {
-	rv := reflect.ValueOf(v) // convert by dynamic interface type
-	if code.AddrForMarshaler {
-		if rv.CanAddr() {
-			rv = rv.Addr()
-		} else {
-			newV := reflect.New(rv.Type())
-			newV.Elem().Set(rv)
-			rv = newV
+	var compacted []byte
+	if code.TypeEncoder != nil {
+		bb, err := appendTypeEncoder(code, nil, v)
+		if err != nil {
+			return nil, err
 		}
-	}
-	v = rv.Interface()
-	marshaler, ok := v.(json.Marshaler)
-	if !ok {
-		return AppendNull(b), nil
-	}
-	bb, err := marshaler.MarshalJSON()
-	if err != nil {
-		return nil, &errors.MarshalerError{Type: reflect.TypeOf(v), Err: err}
-	}
-	var compactBuf bytes.Buffer
-	if err := Compact(&compactBuf, bb, escape); err != nil {
-		return nil, &errors.MarshalerError{Type: reflect.TypeOf(v), Err: err}
+		compacted = bb
+	} else {
+		rv := reflect.ValueOf(v) // convert by dynamic interface type
+		if code.AddrForMarshaler {
+			if rv.CanAddr() {
+				rv = rv.Addr()
+			} else {
+				newV := reflect.New(rv.Type())
+				newV.Elem().Set(rv)
+				rv = newV
+			}
+		}
+		v = rv.Interface()
+		bb, ok, err := callMarshalJSON(ctx, v)
+		if !ok {
+			return AppendNull(b), nil
+		}
+		if err != nil {
+			return nil, &errors.MarshalerError{Type: reflect.TypeOf(v), Err: err}
+		}
+		var compactBuf bytes.Buffer
+		if err := Compact(&compactBuf, bb, escape); err != nil {
+			return nil, &errors.MarshalerError{Type: reflect.TypeOf(v), Err: err}
+		}
+		compacted = compactBuf.Bytes()
 	}
 	var indentBuf bytes.Buffer
 	if err := Indent(
 		&indentBuf,
-		compactBuf.Bytes(),
+		compacted,
 		string(ctx.Prefix)+strings.Repeat(string(ctx.IndentStr), ctx.BaseIndent+indent),
 		string(ctx.IndentStr),
 	); err != nil {
@@ -0,0 +1,116 @@
+package encoder
+
+import (
+	"math"
+	"reflect"
+	"unsafe"
+
+	"github.com/goccy/go-json/internal/runtime"
+)
+
+type isZeroer interface {
+	IsZero() bool
+}
+
+var isZeroerType = reflect.TypeOf((*isZeroer)(nil)).Elem()
+
+// IsZeroFunc reports whether the struct field at p has the zero value.
+type IsZeroFunc func(p unsafe.Pointer) bool
+
+// isOmitZeroWithOmitEmptyCode reports whether the operations of omitempty omit exactly the zero values of typ.
+// Such fields are encoded by the operations of omitempty to avoid calling IsZeroFunc.
+func isOmitZeroWithOmitEmptyCode(code *Opcode, typ *runtime.Type) bool {
+	if typ.Implements(isZeroerType) || runtime.PtrTo(typ).Implements(isZeroerType) {
+		return false
+	}
+	switch code.Op {
+	case OpInt, OpUint, OpBool, OpString, OpNumber:
+		// floats are not included, because the operations of omitempty also omit -0, which isn't the zero value
+		return true
+	}
+	return false
+}
+
+// newIsZeroFunc creates IsZeroFunc of typ.
+// IsZero method of typ is used if exists. If orEmpty is true, empty slices and maps are also reported as zero for omitempty.
+func newIsZeroFunc(typ *runtime.Type, orEmpty bool) IsZeroFunc {
+	switch {
+	case typ.Implements(isZeroerType):
+		rtype := runtime.RType2Type(typ)
+		switch typ.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+			return func(p unsafe.Pointer) bool {
+				v := reflect.NewAt(rtype, p).Elem()
+				return v.IsNil() || v.Interface().(isZeroer).IsZero()
+			}
+		}
+		indirect := runtime.IfaceIndir(typ)
+		return func(p unsafe.Pointer) bool {
+			if !indirect {
+				p = *(*unsafe.Pointer)(p)
+			}
+			v := *(*interface{})(unsafe.Pointer(&emptyInterface{typ: typ, ptr: p}))
+			return v.(isZeroer).IsZero()
+		}
+	case runtime.PtrTo(typ).Implements(isZeroerType):
+		ptrType := runtime.PtrTo(typ)
+		return func(p unsafe.Pointer) bool {
+			v := *(*interface{})(unsafe.Pointer(&emptyInterface{typ: ptrType, ptr: p}))
+			return v.(isZeroer).IsZero()
+		}
+	}
+	switch typ.Kind() {
+	case reflect.Ptr, reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return func(p unsafe.Pointer) bool {
+			return *(*unsafe.Pointer)(p) == nil
+		}
+	case reflect.Map:
+		return func(p unsafe.Pointer) bool {
+			m := *(*unsafe.Pointer)(p)
+			return m == nil || (orEmpty && MapLen(m) == 0)
+		}
+	case reflect.Slice:
+		return func(p unsafe.Pointer) bool {
+			slice := (*runtime.SliceHeader)(p)
+			return slice.Data == nil || (orEmpty && slice.Len == 0)
+		}
+	case reflect.Interface:
+		return func(p unsafe.Pointer) bool {
+			return (*emptyInterface)(p).typ == nil
+		}
+	case reflect.String:
+		return func(p unsafe.Pointer) bool {
+			return len(*(*string)(p)) == 0
+		}
+	case reflect.Bool:
+		return func(p unsafe.Pointer) bool {
+			return !*(*bool)(p)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		switch typ.Size() {
+		case 1:
+			return func(p unsafe.Pointer) bool { return *(*uint8)(p) == 0 }
+		case 2:
+			return func(p unsafe.Pointer) bool { return *(*uint16)(p) == 0 }
+		case 4:
+			return func(p unsafe.Pointer) bool { return *(*uint32)(p) == 0 }
+		default:
+			return func(p unsafe.Pointer) bool { return *(*uint64)(p) == 0 }
+		}
+	case reflect.Float32:
+		return func(p unsafe.Pointer) bool {
+			f := *(*float32)(p)
+			return math.Float32bits(f) == 0 || (orEmpty && f == 0)
+		}
+	case reflect.Float64:
+		return func(p unsafe.Pointer) bool {
+			f := *(*float64)(p)
+			return math.Float64bits(f) == 0 || (orEmpty && f == 0)
+		}
+	}
+	rtype := runtime.RType2Type(typ)
+	return func(p unsafe.Pointer) bool {
+		return reflect.NewAt(rtype, p).Elem().IsZero()
+	}
+}
@@ -27,6 +27,7 @@ type Opcode struct {
 	AddrForMarshaler bool          // whether needs to addr for marshaler or not
 	IsNextOpPtrType  bool          // whether next operation is ptr type or not
 	IsNilableType    bool          // whether type is nilable or not
+	IsNonEmptyIface  bool          // whether interface type has methods or not
 	RshiftNum        uint8         // use to take bit for judging whether negative integer or not
 	Mask             uint64        // mask for number
 	Indent           int           // indent number
@@ -40,14 +41,20 @@ type Opcode struct {
 	Offset  uintptr // offset size from struct header
 	Size    uintptr // array/slice elem size
 
-	MapKey    *Opcode       // map key
-	MapValue  *Opcode       // map value
-	Elem      *Opcode       // array/slice elem
-	End       *Opcode       // array/slice/struct/map end
-	PrevField *Opcode       // prev struct field
-	NextField *Opcode       // next struct field
-	Next      *Opcode       // next opcode
-	Jmp       *CompiledCode // for recursive call
+	MapKey    *Opcode        // map key
+	MapValue  *Opcode        // map value
+	Elem      *Opcode        // array/slice elem
+	End       *Opcode        // array/slice/struct/map end
+	PrevField *Opcode        // prev struct field
+	NextField *Opcode        // next struct field
+	Next      *Opcode        // next opcode
+	Jmp       *CompiledCode  // for recursive call
+	Fields    *FieldQuery    // selected map keys
+	Config    *CompileConfig // configuration to compile the value of interface, inline map or MapSlice
+	UnionName string         // value of the key of the union written by OpUnionKey
+
+	TypeEncoder TypeEncoderFunc // encoder registered for the type
+	IsZero      IsZeroFunc      // zero value checker of omitzero field
 }
 
 func rshitNum(bitSize uint8) uint8 {
@@ -244,6 +251,7 @@ func (c *Opcode) copy(codeMap map[uintptr]*Opcode) *Opcode {
 		AddrForMarshaler: c.AddrForMarshaler,
 		IsNextOpPtrType:  c.IsNextOpPtrType,
 		IsNilableType:    c.IsNilableType,
+		IsNonEmptyIface:  c.IsNonEmptyIface,
 		Indent:           c.Indent,
 		Idx:              c.Idx,
 		HeadIdx:          c.HeadIdx,
@@ -263,6 +271,11 @@ func (c *Opcode) copy(codeMap map[uintptr]*Opcode) *Opcode {
 	copied.NextField = c.NextField.copy(codeMap)
 	copied.Next = c.Next.copy(codeMap)
 	copied.Jmp = c.Jmp
+	copied.Fields = c.Fields
+	copied.Config = c.Config
+	copied.UnionName = c.UnionName
+	copied.TypeEncoder = c.TypeEncoder
+	copied.IsZero = c.IsZero
 	return copied
 }
 
@@ -636,13 +649,50 @@ func newMapEndCode(ctx *compileContext, head *Opcode) *Opcode {
 }
 
 func newInterfaceCode(ctx *compileContext) *Opcode {
+	config := ctx.compileConfig()
+	if union := runtime.LookupUnion(ctx.typ); union != nil {
+		if config == nil {
+			config = &CompileConfig{}
+		}
+		config.Union = union
+	}
+	return &Opcode{
+		Op:              OpInterface,
+		Type:            ctx.typ,
+		DisplayIdx:      ctx.opcodeIndex,
+		Idx:             opcodeOffset(ctx.ptrIndex),
+		Indent:          ctx.indent,
+		IsNonEmptyIface: ctx.typ.NumMethod() > 0,
+		Next:            newEndOp(ctx),
+		Config:          config,
+	}
+}
+
+// newUnionKeyCode returns the code that opens the object of the struct of head and writes key with name before the fields.
+func newUnionKeyCode(head *Opcode, key, name string) *Opcode {
+	head.AnonymousHead = true
+	return &Opcode{
+		Op:         OpUnionKey,
+		Type:       head.Type,
+		DisplayIdx: head.DisplayIdx,
+		Idx:        head.Idx,
+		Indent:     head.Indent,
+		Key:        []byte(fmt.Sprintf(`"%s":`, key)),
+		EscapedKey: []byte(fmt.Sprintf(`%s:`, string(AppendEscapedString([]byte{}, key)))),
+		UnionName:  name,
+		Next:       head,
+	}
+}
+
+func newMapSliceCode(ctx *compileContext) *Opcode {
 	return &Opcode{
-		Op:         OpInterface,
+		Op:         OpMapSlice,
 		Type:       ctx.typ,
 		DisplayIdx: ctx.opcodeIndex,
 		Idx:        opcodeOffset(ctx.ptrIndex),
 		Indent:     ctx.indent,
 		Next:       newEndOp(ctx),
+		Config:     ctx.compileConfig(),
 	}
 }
 
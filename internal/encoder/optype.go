@@ -22,7 +22,7 @@ const (
 	CodeStructEnd   CodeType = 11
 )
 
-var opTypeStrings = [416]string{
+var opTypeStrings = [437]string{
 	"End",
 	"Interface",
 	"Ptr",
@@ -439,6 +439,27 @@ var opTypeStrings = [416]string{
 	"StructEnd",
 	"StructEndOmitEmpty",
 	"StructEndStringTag",
+	"StructHeadOmitZero",
+	"StructPtrHeadOmitZero",
+	"StructFieldOmitZero",
+	"StructHeadInline",
+	"StructPtrHeadInline",
+	"StructFieldInline",
+	"Float32String",
+	"Float64String",
+	"StringString",
+	"BoolString",
+	"NumberString",
+	"IntPtrString",
+	"UintPtrString",
+	"Float32PtrString",
+	"Float64PtrString",
+	"StringPtrString",
+	"BoolPtrString",
+	"NumberPtrString",
+	"UnionKey",
+	"MapSlice",
+	"MapSlicePtr",
 }
 
 type OpType int
@@ -860,10 +881,31 @@ const (
 	OpStructEnd                            OpType = 413
 	OpStructEndOmitEmpty                   OpType = 414
 	OpStructEndStringTag                   OpType = 415
+	OpStructHeadOmitZero                   OpType = 416
+	OpStructPtrHeadOmitZero                OpType = 417
+	OpStructFieldOmitZero                  OpType = 418
+	OpStructHeadInline                     OpType = 419
+	OpStructPtrHeadInline                  OpType = 420
+	OpStructFieldInline                    OpType = 421
+	OpFloat32String                        OpType = 422
+	OpFloat64String                        OpType = 423
+	OpStringString                         OpType = 424
+	OpBoolString                           OpType = 425
+	OpNumberString                         OpType = 426
+	OpIntPtrString                         OpType = 427
+	OpUintPtrString                        OpType = 428
+	OpFloat32PtrString                     OpType = 429
+	OpFloat64PtrString                     OpType = 430
+	OpStringPtrString                      OpType = 431
+	OpBoolPtrString                        OpType = 432
+	OpNumberPtrString                      OpType = 433
+	OpUnionKey                             OpType = 434
+	OpMapSlice                             OpType = 435
+	OpMapSlicePtr                          OpType = 436
 )
 
 func (t OpType) String() string {
-	if int(t) >= 416 {
+	if int(t) >= 437 {
 		return ""
 	}
 	return opTypeStrings[int(t)]
@@ -899,6 +941,12 @@ func (t OpType) CodeType() CodeType {
 }
 
 func (t OpType) HeadToPtrHead() OpType {
+	switch t {
+	case OpStructHeadOmitZero:
+		return OpStructPtrHeadOmitZero
+	case OpStructHeadInline:
+		return OpStructPtrHeadInline
+	}
 	if strings.Index(t.String(), "PtrHead") > 0 {
 		return t
 	}
@@ -934,6 +982,12 @@ func (t OpType) HeadToStringTagHead() OpType {
 }
 
 func (t OpType) PtrHeadToHead() OpType {
+	switch t {
+	case OpStructPtrHeadOmitZero:
+		return OpStructHeadOmitZero
+	case OpStructPtrHeadInline:
+		return OpStructHeadInline
+	}
 	idx := strings.Index(t.String(), "Ptr")
 	if idx == -1 {
 		return t
@@ -0,0 +1,172 @@
+package encoder
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// FieldQuery is a tree of the struct fields and map keys selected for encoding.
+// A nil *FieldQuery selects everything.
+type FieldQuery struct {
+	fields   map[string]*FieldQuery // nil selects all the map keys except the excluded ones
+	excluded map[string]struct{}    // map keys that aren't selected
+	hash     string                 // canonical representation of the query
+}
+
+// NewFieldQuery creates a FieldQuery from field names.
+// A nested field is specified by joining the keys with dots ( e.g. "owner.email" ).
+func NewFieldQuery(fields []string) *FieldQuery {
+	q := &FieldQuery{fields: map[string]*FieldQuery{}}
+	for _, field := range fields {
+		q.add(strings.Split(field, "."))
+	}
+	q.setHash()
+	return q
+}
+
+func (q *FieldQuery) setHash() {
+	for _, child := range q.fields {
+		if child != nil {
+			child.setHash()
+		}
+	}
+	q.hash = q.String()
+}
+
+func (q *FieldQuery) add(names []string) {
+	name := names[0]
+	child, exists := q.fields[name]
+	if exists && child == nil {
+		// already selects all of the value
+		return
+	}
+	if len(names) == 1 {
+		q.fields[name] = nil
+		return
+	}
+	if child == nil {
+		child = &FieldQuery{fields: map[string]*FieldQuery{}}
+		q.fields[name] = child
+	}
+	child.add(names[1:])
+}
+
+func (q *FieldQuery) merge(src *FieldQuery) {
+	for name, child := range src.fields {
+		dst, exists := q.fields[name]
+		switch {
+		case exists && dst == nil:
+		case child == nil:
+			q.fields[name] = nil
+		default:
+			if dst == nil {
+				dst = &FieldQuery{fields: map[string]*FieldQuery{}}
+				q.fields[name] = dst
+			}
+			dst.merge(child)
+		}
+	}
+}
+
+// ExcludeMapKeys returns the query that selects the map keys selected by q except keys.
+// It is used for the map of the inline field not to encode the keys of the other fields.
+func (q *FieldQuery) ExcludeMapKeys(keys map[string]struct{}) *FieldQuery {
+	excluded := &FieldQuery{excluded: keys}
+	if q != nil {
+		excluded.fields = q.fields
+	}
+	excluded.setHash()
+	return excluded
+}
+
+// Field returns the query for the value of the field named name
+// and reports whether the field is selected.
+func (q *FieldQuery) Field(name string) (*FieldQuery, bool) {
+	if q.fields == nil {
+		return nil, true
+	}
+	child, exists := q.fields[name]
+	return child, exists
+}
+
+// Elem returns the query for the values of a map selected by q.
+// Since a map value type is shared by all keys, the queries of every selected key are merged.
+func (q *FieldQuery) Elem() *FieldQuery {
+	if q == nil || q.fields == nil {
+		return nil
+	}
+	elem := &FieldQuery{fields: map[string]*FieldQuery{}}
+	for _, child := range q.fields {
+		if child == nil {
+			return nil
+		}
+		elem.merge(child)
+	}
+	elem.setHash()
+	return elem
+}
+
+// SelectsMapKey reports whether the string map key pointed to by key is selected.
+func (q *FieldQuery) SelectsMapKey(key unsafe.Pointer) bool {
+	k := *(*string)(key)
+	if _, excluded := q.excluded[k]; excluded {
+		return false
+	}
+	if q.fields == nil {
+		return true
+	}
+	_, exists := q.fields[k]
+	return exists
+}
+
+func (q *FieldQuery) String() string {
+	if q == nil {
+		return ""
+	}
+	names := make([]string, 0, len(q.fields))
+	for name := range q.fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	if q.fields == nil {
+		b.WriteByte('*')
+	}
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.Quote(name))
+		if child := q.fields[name]; child != nil {
+			b.WriteByte('{')
+			b.WriteString(child.String())
+			b.WriteByte('}')
+		}
+	}
+	if len(q.excluded) > 0 {
+		excluded := make([]string, 0, len(q.excluded))
+		for key := range q.excluded {
+			excluded = append(excluded, key)
+		}
+		sort.Strings(excluded)
+		for _, key := range excluded {
+			b.WriteString(",-")
+			b.WriteString(strconv.Quote(key))
+		}
+	}
+	return b.String()
+}
+
+// SkipUnselectedMapKeys advances the map iterator to the first entry selected by q
+// and returns the index of it. If no more entries are selected, length is returned.
+func SkipUnselectedMapKeys(q *FieldQuery, iter unsafe.Pointer, idx, length uintptr) uintptr {
+	for ; idx < length; idx++ {
+		if q.SelectsMapKey(MapIterKey(iter)) {
+			return idx
+		}
+		MapIterNext(iter)
+	}
+	return idx
+}
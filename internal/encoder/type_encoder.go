@@ -0,0 +1,91 @@
+package encoder
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/goccy/go-json/internal/runtime"
+)
+
+// TypeEncoderFunc appends the JSON encoding of v to b and returns the extended buffer.
+type TypeEncoderFunc func(b []byte, v interface{}) ([]byte, error)
+
+// TypeEncoders is a set of encoders registered for types.
+// The encoders of TypeEncoders are immutable, so it can be shared by compiled opcodes.
+// The opcodes compiled with TypeEncoders are cached in it instead of the global cache,
+// so that they are released with the TypeEncoders of an Encoder.
+type TypeEncoders struct {
+	encoders   map[uintptr]TypeEncoderFunc
+	opcodeSets runtime.ConfigCache // configOpcodeSetKey => *OpcodeSet
+	derived    runtime.ConfigCache // typeEncoderKey => *TypeEncoders returned by With
+}
+
+// typeEncoderKey is the key of the TypeEncoders derived from another one by With.
+type typeEncoderKey struct {
+	typeptr uintptr
+	fn      unsafe.Pointer // the function value, which is different for each closure
+}
+
+// derivedTypeEncoders caches the TypeEncoders derived from the nil *TypeEncoders.
+var derivedTypeEncoders runtime.ConfigCache
+
+// With returns the TypeEncoders that has fn for typ in addition to the encoders of e.
+// A nil *TypeEncoders is treated as empty.
+// The TypeEncoders is shared by the calls with the same arguments ( e.g. Encoders created for each request
+// with the same functions ), so that the opcodes compiled with it are shared as well.
+func (e *TypeEncoders) With(typ reflect.Type, fn TypeEncoderFunc) *TypeEncoders {
+	typeptr := uintptr(unsafe.Pointer(runtime.Type2RType(typ)))
+	key := typeEncoderKey{typeptr: typeptr, fn: *(*unsafe.Pointer)(unsafe.Pointer(&fn))}
+	derived := &derivedTypeEncoders
+	if e != nil {
+		derived = &e.derived
+	}
+	if cached, exists := derived.Load(key); exists {
+		return cached.(*TypeEncoders)
+	}
+	encoders := map[uintptr]TypeEncoderFunc{}
+	if e != nil {
+		for k, v := range e.encoders {
+			encoders[k] = v
+		}
+	}
+	encoders[typeptr] = fn
+	ret := &TypeEncoders{encoders: encoders}
+	derived.Store(key, ret)
+	return ret
+}
+
+// Lookup returns the encoder registered for typ.
+func (e *TypeEncoders) Lookup(typ *runtime.Type) TypeEncoderFunc {
+	if e == nil {
+		return nil
+	}
+	return e.encoders[uintptr(unsafe.Pointer(typ))]
+}
+
+var (
+	globalTypeEncodersMu sync.Mutex
+	globalTypeEncoders   atomic.Value // *TypeEncoders
+)
+
+// RegisterTypeEncoder registers fn as the encoder of typ for every encoding.
+// The opcodes compiled before are discarded, because they may encode typ without fn.
+// The opcodes compiled with a configuration are not discarded but not used either,
+// because the global encoders are a part of the key of them.
+func RegisterTypeEncoder(typ reflect.Type, fn TypeEncoderFunc) {
+	globalTypeEncodersMu.Lock()
+	defer globalTypeEncodersMu.Unlock()
+	globalTypeEncoders.Store(loadGlobalTypeEncoders().With(typ, fn))
+	clearCachedOpcodeSets()
+}
+
+func loadGlobalTypeEncoders() *TypeEncoders {
+	encoders, _ := globalTypeEncoders.Load().(*TypeEncoders)
+	return encoders
+}
+
+func lookupGlobalTypeEncoder(typ *runtime.Type) TypeEncoderFunc {
+	return loadGlobalTypeEncoders().Lookup(typ)
+}
@@ -42,6 +42,15 @@ type emptyInterface struct {
 	ptr unsafe.Pointer
 }
 
+type nonEmptyInterface struct {
+	itab *struct {
+		ityp *runtime.Type // static interface type
+		typ  *runtime.Type // dynamic concrete type
+		// unused fields...
+	}
+	ptr unsafe.Pointer
+}
+
 func Run(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet, opt encoder.Option) ([]byte, error) {
 	recursiveLevel := 0
 	ptrOffset := uintptr(0)
@@ -84,18 +93,123 @@ func Run(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet, opt
 			b = appendUint(b, ptrToUint64(load(ctxptr, code.Idx)), code)
 			b = appendComma(b)
 			code = code.Next
+		case encoder.OpIntPtrString:
+			p := loadNPtr(ctxptr, code.Idx, code.PtrNum)
+			if p == 0 {
+				b = appendNull(b)
+				b = appendComma(b)
+				code = code.Next
+				break
+			}
+			store(ctxptr, code.Idx, p)
+			fallthrough
 		case encoder.OpIntString:
 			b = append(b, '"')
 			b = appendInt(b, ptrToUint64(load(ctxptr, code.Idx)), code)
 			b = append(b, '"')
 			b = appendComma(b)
 			code = code.Next
+		case encoder.OpUintPtrString:
+			p := loadNPtr(ctxptr, code.Idx, code.PtrNum)
+			if p == 0 {
+				b = appendNull(b)
+				b = appendComma(b)
+				code = code.Next
+				break
+			}
+			store(ctxptr, code.Idx, p)
+			fallthrough
 		case encoder.OpUintString:
 			b = append(b, '"')
 			b = appendUint(b, ptrToUint64(load(ctxptr, code.Idx)), code)
 			b = append(b, '"')
 			b = appendComma(b)
 			code = code.Next
+		case encoder.OpFloat32PtrString:
+			p := loadNPtr(ctxptr, code.Idx, code.PtrNum)
+			if p == 0 {
+				b = appendNull(b)
+				b = appendComma(b)
+				code = code.Next
+				break
+			}
+			store(ctxptr, code.Idx, p)
+			fallthrough
+		case encoder.OpFloat32String:
+			b = append(b, '"')
+			b = appendFloat32(b, ptrToFloat32(load(ctxptr, code.Idx)))
+			b = append(b, '"')
+			b = appendComma(b)
+			code = code.Next
+		case encoder.OpFloat64PtrString:
+			p := loadNPtr(ctxptr, code.Idx, code.PtrNum)
+			if p == 0 {
+				b = appendNull(b)
+				b = appendComma(b)
+				code = code.Next
+				break
+			}
+			store(ctxptr, code.Idx, p)
+			fallthrough
+		case encoder.OpFloat64String:
+			v := ptrToFloat64(load(ctxptr, code.Idx))
+			if math.IsInf(v, 0) || math.IsNaN(v) {
+				return nil, encoder.ErrUnsupportedFloat(v)
+			}
+			b = append(b, '"')
+			b = appendFloat64(b, v)
+			b = append(b, '"')
+			b = appendComma(b)
+			code = code.Next
+		case encoder.OpStringPtrString:
+			p := loadNPtr(ctxptr, code.Idx, code.PtrNum)
+			if p == 0 {
+				b = appendNull(b)
+				b = appendComma(b)
+				code = code.Next
+				break
+			}
+			store(ctxptr, code.Idx, p)
+			fallthrough
+		case encoder.OpStringString:
+			b = appendString(b, string(appendString([]byte{}, ptrToString(load(ctxptr, code.Idx)))))
+			b = appendComma(b)
+			code = code.Next
+		case encoder.OpBoolPtrString:
+			p := loadNPtr(ctxptr, code.Idx, code.PtrNum)
+			if p == 0 {
+				b = appendNull(b)
+				b = appendComma(b)
+				code = code.Next
+				break
+			}
+			store(ctxptr, code.Idx, p)
+			fallthrough
+		case encoder.OpBoolString:
+			b = append(b, '"')
+			b = appendBool(b, ptrToBool(load(ctxptr, code.Idx)))
+			b = append(b, '"')
+			b = appendComma(b)
+			code = code.Next
+		case encoder.OpNumberPtrString:
+			p := loadNPtr(ctxptr, code.Idx, code.PtrNum)
+			if p == 0 {
+				b = appendNull(b)
+				b = appendComma(b)
+				code = code.Next
+				break
+			}
+			store(ctxptr, code.Idx, p)
+			fallthrough
+		case encoder.OpNumberString:
+			b = append(b, '"')
+			bb, err := appendNumber(b, ptrToNumber(load(ctxptr, code.Idx)))
+			if err != nil {
+				return nil, err
+			}
+			b = append(bb, '"')
+			b = appendComma(b)
+			code = code.Next
 		case encoder.OpFloat32Ptr:
 			p := loadNPtr(ctxptr, code.Idx, code.PtrNum)
 			if p == 0 {
@@ -219,7 +333,11 @@ func Run(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet, opt
 				break
 			}
 			ctx.KeepRefs = append(ctx.KeepRefs, unsafe.Pointer(iface))
-			ifaceCodeSet, err := encoder.CompileToGetCodeSet(uintptr(unsafe.Pointer(iface.typ)))
+			typ := iface.typ
+			if code.IsNonEmptyIface {
+				typ = (*nonEmptyInterface)(unsafe.Pointer(iface)).itab.typ
+			}
+			ifaceCodeSet, err := encoder.CompileToGetCodeSetWithConfig(uintptr(unsafe.Pointer(typ)), code.Config)
 			if err != nil {
 				return nil, err
 			}
@@ -250,6 +368,37 @@ func Run(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet, opt
 			ctxptr = ctx.Ptr()
 			ctx.SeenPtr = ctx.SeenPtr[:len(ctx.SeenPtr)-1]
 
+			b = bb
+			code = code.Next
+		case encoder.OpUnionKey:
+			b = append(b, '{')
+			b = append(b, code.EscapedKey...)
+			b = appendString(b, code.UnionName)
+			b = appendComma(b)
+			code = code.Next
+		case encoder.OpMapSlicePtr:
+			p := loadNPtr(ctxptr, code.Idx, code.PtrNum)
+			if p == 0 {
+				b = appendNull(b)
+				b = appendComma(b)
+				code = code.Next
+				break
+			}
+			store(ctxptr, code.Idx, p)
+			fallthrough
+		case encoder.OpMapSlice:
+			p := load(ctxptr, code.Idx)
+			if p == 0 {
+				b = appendNull(b)
+				b = appendComma(b)
+				code = code.Next
+				break
+			}
+			bb, err := appendMapSlice(ctx, codeSet, ptrOffset, b, code, p, opt)
+			if err != nil {
+				return nil, err
+			}
+			ctxptr = ctx.Ptr() + ptrOffset
 			b = bb
 			code = code.Next
 		case encoder.OpMarshalJSONPtr:
@@ -273,7 +422,7 @@ func Run(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet, opt
 			if code.IsNilableType && code.Indirect {
 				p = ptrToPtr(p)
 			}
-			bb, err := appendMarshalJSON(code, b, ptrToInterface(code, p), true)
+			bb, err := appendMarshalJSON(ctx, code, b, ptrToInterface(code, p), true)
 			if err != nil {
 				return nil, err
 			}
@@ -419,10 +568,19 @@ func Run(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet, opt
 				code = code.End.Next
 				break
 			}
-			b = append(b, '{')
 			iter := mapiterinit(code.Type, uptr)
 			ctx.KeepRefs = append(ctx.KeepRefs, iter)
-			store(ctxptr, code.ElemIdx, 0)
+			var idx uintptr
+			if code.Fields != nil {
+				idx = encoder.SkipUnselectedMapKeys(code.Fields, iter, 0, uintptr(mlen))
+				if idx == uintptr(mlen) {
+					b = append(b, '{', '}', ',')
+					code = code.End.Next
+					break
+				}
+			}
+			b = append(b, '{')
+			store(ctxptr, code.ElemIdx, idx)
 			store(ctxptr, code.Length, uintptr(mlen))
 			store(ctxptr, code.MapIter, uintptr(iter))
 			if (opt & encoder.UnorderedMapOption) == 0 {
@@ -438,6 +596,9 @@ func Run(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet, opt
 			idx := load(ctxptr, code.ElemIdx)
 			length := load(ctxptr, code.Length)
 			idx++
+			if code.Fields != nil {
+				idx = encoder.SkipUnselectedMapKeys(code.Fields, ptrToUnsafePtr(load(ctxptr, code.MapIter)), idx, length)
+			}
 			if (opt & encoder.UnorderedMapOption) != 0 {
 				if idx < length {
 					ptr := load(ctxptr, code.MapIter)
@@ -484,10 +645,12 @@ func Run(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet, opt
 			code = code.Next
 		case encoder.OpMapEnd:
 			// this operation only used by sorted map.
-			length := int(load(ctxptr, code.Length))
 			ptr := load(ctxptr, code.MapPos)
 			mapCtx := (*encoder.MapContext)(ptrToUnsafePtr(ptr))
 			pos := mapCtx.Pos
+			// pos has the start of the first key and the start of value and next key for each entry.
+			// use it instead of the map length because unselected entries are skipped.
+			length := len(pos) / 2
 			for i := 0; i < length; i++ {
 				startKey := pos[i*2]
 				startValue := pos[i*2+1]
@@ -628,6 +791,74 @@ func Run(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet, opt
 				code = code.Next
 				store(ctxptr, code.Idx, p)
 			}
+		case encoder.OpStructPtrHeadOmitZero:
+			p := load(ctxptr, code.Idx)
+			if p == 0 {
+				if !code.AnonymousHead {
+					b = appendNull(b)
+					b = appendComma(b)
+				}
+				code = code.End.Next
+				break
+			}
+			store(ctxptr, code.Idx, ptrToNPtr(p, code.PtrNum))
+			fallthrough
+		case encoder.OpStructHeadOmitZero:
+			p := load(ctxptr, code.Idx)
+			if p == 0 && (code.Indirect || code.Next.Op == encoder.OpStructEnd) {
+				if !code.AnonymousHead {
+					b = appendNull(b)
+					b = appendComma(b)
+				}
+				code = code.End.Next
+				break
+			}
+			if !code.AnonymousHead {
+				b = append(b, '{')
+			}
+			p += code.Offset
+			if p == 0 || code.IsZero(ptrToUnsafePtr(p)) {
+				code = code.NextField
+			} else {
+				b = append(b, code.EscapedKey...)
+				code = code.Next
+				store(ctxptr, code.Idx, p)
+			}
+		case encoder.OpStructPtrHeadInline:
+			p := load(ctxptr, code.Idx)
+			if p == 0 {
+				if !code.AnonymousHead {
+					b = appendNull(b)
+					b = appendComma(b)
+				}
+				code = code.End.Next
+				break
+			}
+			store(ctxptr, code.Idx, ptrToNPtr(p, code.PtrNum))
+			fallthrough
+		case encoder.OpStructHeadInline:
+			p := load(ctxptr, code.Idx)
+			if p == 0 && code.Indirect {
+				if !code.AnonymousHead {
+					b = appendNull(b)
+					b = appendComma(b)
+				}
+				code = code.End.Next
+				break
+			}
+			if !code.AnonymousHead {
+				b = append(b, '{')
+			}
+			if p != 0 && code.Indirect {
+				p = ptrToPtr(p + code.Offset)
+			}
+			bb, err := appendInlineMap(ctx, codeSet, ptrOffset, b, code, p, opt)
+			if err != nil {
+				return nil, err
+			}
+			ctxptr = ctx.Ptr() + ptrOffset
+			b = bb
+			code = code.Next
 		case encoder.OpStructPtrHeadStringTag:
 			p := load(ctxptr, code.Idx)
 			if p == 0 {
@@ -2718,7 +2949,7 @@ func Run(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet, opt
 			if p == 0 && code.Nilcheck {
 				b = appendNull(b)
 			} else {
-				bb, err := appendMarshalJSON(code, b, ptrToInterface(code, p), true)
+				bb, err := appendMarshalJSON(ctx, code, b, ptrToInterface(code, p), true)
 				if err != nil {
 					return nil, err
 				}
@@ -2762,7 +2993,7 @@ func Run(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet, opt
 			if p == 0 && code.Nilcheck {
 				b = appendNull(b)
 			} else {
-				bb, err := appendMarshalJSON(code, b, ptrToInterface(code, p), true)
+				bb, err := appendMarshalJSON(ctx, code, b, ptrToInterface(code, p), true)
 				if err != nil {
 					return nil, err
 				}
@@ -2807,7 +3038,7 @@ func Run(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet, opt
 				code = code.NextField
 			} else {
 				b = append(b, code.EscapedKey...)
-				bb, err := appendMarshalJSON(code, b, iface, true)
+				bb, err := appendMarshalJSON(ctx, code, b, iface, true)
 				if err != nil {
 					return nil, err
 				}
@@ -2847,7 +3078,7 @@ func Run(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet, opt
 			if p == 0 {
 				b = appendNull(b)
 			} else {
-				bb, err := appendMarshalJSON(code, b, ptrToInterface(code, p), true)
+				bb, err := appendMarshalJSON(ctx, code, b, ptrToInterface(code, p), true)
 				if err != nil {
 					return nil, err
 				}
@@ -2887,7 +3118,7 @@ func Run(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet, opt
 				code = code.NextField
 			} else {
 				b = append(b, code.EscapedKey...)
-				bb, err := appendMarshalJSON(code, b, ptrToInterface(code, p), true)
+				bb, err := appendMarshalJSON(ctx, code, b, ptrToInterface(code, p), true)
 				if err != nil {
 					return nil, err
 				}
@@ -3124,6 +3355,25 @@ func Run(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet, opt
 				code = code.Next
 				store(ctxptr, code.Idx, p)
 			}
+		case encoder.OpStructFieldOmitZero:
+			p := load(ctxptr, code.HeadIdx)
+			p += code.Offset
+			if code.IsZero(ptrToUnsafePtr(p)) {
+				code = code.NextField
+			} else {
+				b = append(b, code.EscapedKey...)
+				code = code.Next
+				store(ctxptr, code.Idx, p)
+			}
+		case encoder.OpStructFieldInline:
+			p := load(ctxptr, code.HeadIdx)
+			bb, err := appendInlineMap(ctx, codeSet, ptrOffset, b, code, ptrToPtr(p+code.Offset), opt)
+			if err != nil {
+				return nil, err
+			}
+			ctxptr = ctx.Ptr() + ptrOffset
+			b = bb
+			code = code.Next
 		case encoder.OpStructFieldStringTag:
 			p := load(ctxptr, code.HeadIdx)
 			p += code.Offset
@@ -3628,7 +3878,7 @@ func Run(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet, opt
 			if p == 0 && code.Nilcheck {
 				b = appendNull(b)
 			} else {
-				bb, err := appendMarshalJSON(code, b, ptrToInterface(code, p), true)
+				bb, err := appendMarshalJSON(ctx, code, b, ptrToInterface(code, p), true)
 				if err != nil {
 					return nil, err
 				}
@@ -3647,7 +3897,7 @@ func Run(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet, opt
 				break
 			}
 			b = append(b, code.EscapedKey...)
-			bb, err := appendMarshalJSON(code, b, ptrToInterface(code, p), true)
+			bb, err := appendMarshalJSON(ctx, code, b, ptrToInterface(code, p), true)
 			if err != nil {
 				return nil, err
 			}
@@ -3660,7 +3910,7 @@ func Run(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet, opt
 			if p == 0 {
 				b = appendNull(b)
 			} else {
-				bb, err := appendMarshalJSON(code, b, ptrToInterface(code, p), true)
+				bb, err := appendMarshalJSON(ctx, code, b, ptrToInterface(code, p), true)
 				if err != nil {
 					return nil, err
 				}
@@ -3673,7 +3923,7 @@ func Run(ctx *encoder.RuntimeContext, b []byte, codeSet *encoder.OpcodeSet, opt
 			p = ptrToNPtr(p+code.Offset, code.PtrNum)
 			if p != 0 {
 				b = append(b, code.EscapedKey...)
-				bb, err := appendMarshalJSON(code, b, ptrToInterface(code, p), true)
+				bb, err := appendMarshalJSON(ctx, code, b, ptrToInterface(code, p), true)
 				if err != nil {
 					return nil, err
 				}
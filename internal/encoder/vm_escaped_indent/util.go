@@ -88,3 +88,128 @@ func appendIndent(ctx *encoder.RuntimeContext, b []byte, indent int) []byte {
 	b = append(b, ctx.Prefix...)
 	return append(b, bytes.Repeat(ctx.IndentStr, ctx.BaseIndent+indent)...)
 }
+
+// appendInlineMap appends the entries of the map at p to the object that is being encoded.
+// The map is encoded by the code set of its type with the indent of the entries, and then its braces are removed.
+func appendInlineMap(ctx *encoder.RuntimeContext, codeSet *encoder.OpcodeSet, ptrOffset uintptr, b []byte, code *encoder.Opcode, p uintptr, indent int, opt encoder.Option) ([]byte, error) {
+	if p == 0 || maplen(ptrToUnsafePtr(p)) == 0 {
+		return b, nil
+	}
+	mapCodeSet, err := encoder.CompileToGetCodeSetWithConfig(uintptr(unsafe.Pointer(code.Type)), code.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	totalLength := uintptr(codeSet.CodeLength)
+	nextTotalLength := uintptr(mapCodeSet.CodeLength)
+
+	curlen := uintptr(len(ctx.Ptrs))
+	offsetNum := ptrOffset / uintptrSize
+
+	newLen := offsetNum + totalLength + nextTotalLength
+	if curlen < newLen {
+		ctx.Ptrs = append(ctx.Ptrs, make([]uintptr, newLen-curlen)...)
+	}
+	oldPtrs := ctx.Ptrs
+
+	newPtrs := ctx.Ptrs[(ptrOffset+totalLength*uintptrSize)/uintptrSize:]
+	newPtrs[0] = p
+
+	ctx.Ptrs = newPtrs
+
+	oldBaseIndent := ctx.BaseIndent
+	// the entries of the map are indented one level deeper than the map itself
+	ctx.BaseIndent += indent - 1
+	start := len(b)
+	b, err = Run(ctx, b, mapCodeSet, opt)
+	if err != nil {
+		return nil, err
+	}
+	ctx.BaseIndent = oldBaseIndent
+	ctx.Ptrs = oldPtrs
+
+	if b[start] != '{' || len(b)-start == len("{},\n") {
+		// null by MarshalJSON or all the keys are unselected
+		return b[:start], nil
+	}
+	// "{\n<entries>\n<indent>},\n" => "<entries>,\n"
+	end := bytes.LastIndexByte(b[:len(b)-1], '\n')
+	b = append(b[:start], b[start+2:end]...)
+	return appendComma(b), nil
+}
+
+// appendMapSlice encodes the items of the MapSlice at p as an object in the order of the slice.
+func appendMapSlice(ctx *encoder.RuntimeContext, codeSet *encoder.OpcodeSet, ptrOffset uintptr, b []byte, code *encoder.Opcode, p uintptr, opt encoder.Option) ([]byte, error) {
+	items := *(*runtime.MapSlice)(ptrToUnsafePtr(p))
+	if items == nil {
+		return appendComma(appendNull(b)), nil
+	}
+	if len(items) == 0 {
+		return appendComma(append(b, '{', '}')), nil
+	}
+	b = append(b, '{', '\n')
+	for i := range items {
+		b = appendIndent(ctx, b, code.Indent+1)
+		b = appendString(b, items[i].Key)
+		b = append(b, ':', ' ')
+		bb, err := appendMapSliceValue(ctx, codeSet, ptrOffset, b, code, uintptr(unsafe.Pointer(&items[i].Value)), opt)
+		if err != nil {
+			return nil, err
+		}
+		b = bb
+	}
+	// to remove ',' and '\n' characters
+	b = b[:len(b)-2]
+	b = append(b, '\n')
+	b = appendIndent(ctx, b, code.Indent)
+	b = append(b, '}')
+	return appendComma(b), nil
+}
+
+// appendMapSliceValue encodes the value of a MapSlice item in the same way as OpInterface.
+func appendMapSliceValue(ctx *encoder.RuntimeContext, codeSet *encoder.OpcodeSet, ptrOffset uintptr, b []byte, code *encoder.Opcode, p uintptr, opt encoder.Option) ([]byte, error) {
+	for _, seen := range ctx.SeenPtr {
+		if p == seen {
+			return nil, errUnsupportedValue(code, p)
+		}
+	}
+	iface := (*emptyInterface)(ptrToUnsafePtr(p))
+	if iface.ptr == nil {
+		return appendComma(appendNull(b)), nil
+	}
+	ctx.SeenPtr = append(ctx.SeenPtr, p)
+	ctx.KeepRefs = append(ctx.KeepRefs, unsafe.Pointer(iface))
+	ifaceCodeSet, err := encoder.CompileToGetCodeSetWithConfig(uintptr(unsafe.Pointer(iface.typ)), code.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	totalLength := uintptr(codeSet.CodeLength)
+	nextTotalLength := uintptr(ifaceCodeSet.CodeLength)
+
+	curlen := uintptr(len(ctx.Ptrs))
+	offsetNum := ptrOffset / uintptrSize
+
+	newLen := offsetNum + totalLength + nextTotalLength
+	if curlen < newLen {
+		ctx.Ptrs = append(ctx.Ptrs, make([]uintptr, newLen-curlen)...)
+	}
+	oldPtrs := ctx.Ptrs
+
+	newPtrs := ctx.Ptrs[(ptrOffset+totalLength*uintptrSize)/uintptrSize:]
+	newPtrs[0] = uintptr(iface.ptr)
+
+	ctx.Ptrs = newPtrs
+
+	oldBaseIndent := ctx.BaseIndent
+	// the value is indented as the entries of the object
+	ctx.BaseIndent += code.Indent + 1
+	b, err = Run(ctx, b, ifaceCodeSet, opt)
+	if err != nil {
+		return nil, err
+	}
+	ctx.BaseIndent = oldBaseIndent
+	ctx.Ptrs = oldPtrs
+	ctx.SeenPtr = ctx.SeenPtr[:len(ctx.SeenPtr)-1]
+	return b, nil
+}
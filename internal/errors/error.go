@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"reflect"
 	"strconv"
+	"strings"
 )
 
 type InvalidUTF8Error struct {
@@ -49,12 +50,97 @@ func (e *MarshalerError) Unwrap() error { return e.Err }
 
 // A SyntaxError is a description of a JSON syntax error.
 type SyntaxError struct {
-	msg    string // description of error
-	Offset int64  // error occurred after reading Offset bytes
+	msg          string        // description of error
+	Offset       int64         // error occurred after reading Offset bytes
+	Path         string        // path to the value that caused the error ( e.g. orders[3].items[0].price )
+	PathElements []PathElement // structured form of Path
+
+	leafPath []PathElement // elements added by PrependPath, leaf first
 }
 
 func (e *SyntaxError) Error() string { return e.msg }
 
+// An InvalidPathError describes a JSON Path expression that could not be parsed.
+type InvalidPathError struct {
+	msg    string // description of error
+	Path   string // the path expression
+	Offset int    // error occurred at Offset of Path
+}
+
+func (e *InvalidPathError) Error() string {
+	return fmt.Sprintf("json: invalid path %s: %s at offset %d", strconv.Quote(e.Path), e.msg, e.Offset)
+}
+
+// An InvalidPointerError describes a JSON Pointer that could not be parsed.
+type InvalidPointerError struct {
+	msg     string // description of error
+	Pointer string // the pointer
+	Offset  int    // error occurred at Offset of Pointer
+}
+
+func (e *InvalidPointerError) Error() string {
+	return fmt.Sprintf("json: invalid pointer %s: %s at offset %d", strconv.Quote(e.Pointer), e.msg, e.Offset)
+}
+
+// A PointerNotFoundError is returned when a JSON Pointer doesn't reference a value in the document.
+type PointerNotFoundError struct {
+	Pointer string // the pointer
+	Token   string // the reference token that could not be resolved
+}
+
+func (e *PointerNotFoundError) Error() string {
+	return fmt.Sprintf("json: pointer %s not found: no value for %s", strconv.Quote(e.Pointer), strconv.Quote(e.Token))
+}
+
+// A DepthLimitError is returned when values are nested deeper than DecodeLimits.MaxDepth.
+type DepthLimitError struct {
+	Limit  int64 // the configured limit
+	Offset int64 // error occurred after reading Offset bytes
+}
+
+func (e *DepthLimitError) Error() string {
+	return fmt.Sprintf("json: exceeded max depth %d at offset %d", e.Limit, e.Offset)
+}
+
+// A BytesLimitError is returned when the input is larger than DecodeLimits.MaxBytes.
+type BytesLimitError struct {
+	Limit int64 // the configured limit
+}
+
+func (e *BytesLimitError) Error() string {
+	return fmt.Sprintf("json: exceeded max input size %d bytes", e.Limit)
+}
+
+// A StringLimitError is returned when a string is longer than DecodeLimits.MaxStringBytes.
+type StringLimitError struct {
+	Limit  int64 // the configured limit
+	Offset int64 // error occurred after reading Offset bytes
+}
+
+func (e *StringLimitError) Error() string {
+	return fmt.Sprintf("json: exceeded max string length %d bytes at offset %d", e.Limit, e.Offset)
+}
+
+// An ElementsLimitError is returned when an array has more elements than DecodeLimits.MaxElements.
+type ElementsLimitError struct {
+	Limit  int64 // the configured limit
+	Offset int64 // error occurred after reading Offset bytes
+}
+
+func (e *ElementsLimitError) Error() string {
+	return fmt.Sprintf("json: exceeded max array elements %d at offset %d", e.Limit, e.Offset)
+}
+
+// A KeysLimitError is returned when an object has more keys than DecodeLimits.MaxKeys.
+type KeysLimitError struct {
+	Limit  int64 // the configured limit
+	Offset int64 // error occurred after reading Offset bytes
+}
+
+func (e *KeysLimitError) Error() string {
+	return fmt.Sprintf("json: exceeded max object keys %d at offset %d", e.Limit, e.Offset)
+}
+
 // An UnmarshalFieldError describes a JSON object key that
 // led to an unexported (and therefore unwritable) struct field.
 //
@@ -74,11 +160,15 @@ func (e *UnmarshalFieldError) Error() string {
 // An UnmarshalTypeError describes a JSON value that was
 // not appropriate for a value of a specific Go type.
 type UnmarshalTypeError struct {
-	Value  string       // description of JSON value - "bool", "array", "number -5"
-	Type   reflect.Type // type of Go value it could not be assigned to
-	Offset int64        // error occurred after reading Offset bytes
-	Struct string       // name of the struct type containing the field
-	Field  string       // the full path from root node to the field
+	Value        string        // description of JSON value - "bool", "array", "number -5"
+	Type         reflect.Type  // type of Go value it could not be assigned to
+	Offset       int64         // error occurred after reading Offset bytes
+	Struct       string        // name of the struct type containing the field
+	Field        string        // the full path from root node to the field
+	Path         string        // path to the value that caused the error ( e.g. orders[3].items[0].price )
+	PathElements []PathElement // structured form of Path
+
+	leafPath []PathElement // elements added by PrependPath, leaf first
 }
 
 func (e *UnmarshalTypeError) Error() string {
@@ -90,6 +180,181 @@ func (e *UnmarshalTypeError) Error() string {
 	return fmt.Sprintf("json: cannot unmarshal %s into Go value of type %s", e.Value, e.Type)
 }
 
+// A MissingFieldError is returned when an object doesn't have the keys of
+// the struct fields that have the required option.
+type MissingFieldError struct {
+	Struct       string        // name of the struct type
+	Keys         []string      // keys of the required fields that the object doesn't have
+	Offset       int64         // error occurred after reading Offset bytes
+	Path         string        // path to the object that caused the error ( e.g. orders[3].items[0] )
+	PathElements []PathElement // structured form of Path
+
+	leafPath []PathElement // elements added by PrependPath, leaf first
+}
+
+func (e *MissingFieldError) Error() string {
+	keys := make([]string, 0, len(e.Keys))
+	for _, key := range e.Keys {
+		keys = append(keys, strconv.Quote(key))
+	}
+	field := "field"
+	if len(keys) > 1 {
+		field = "fields"
+	}
+	if e.Struct != "" {
+		return fmt.Sprintf("json: missing required %s %s of Go struct %s", field, strings.Join(keys, ", "), e.Struct)
+	}
+	return fmt.Sprintf("json: missing required %s %s", field, strings.Join(keys, ", "))
+}
+
+// DecodeErrors is the list of the errors collected by decoding with the CollectErrors option.
+// Each error is either *UnmarshalTypeError or *MissingFieldError, and the errors are ordered by their offsets.
+type DecodeErrors []error
+
+func (e DecodeErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		path := errorPath(err)
+		if path == "" {
+			msgs = append(msgs, err.Error())
+			continue
+		}
+		msgs = append(msgs, fmt.Sprintf("%s (at %s)", err.Error(), path))
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func errorPath(err error) string {
+	switch e := err.(type) {
+	case *UnmarshalTypeError:
+		return e.Path
+	case *MissingFieldError:
+		return e.Path
+	}
+	return ""
+}
+
+// A PathElement is an object member or an array element on the path to a JSON value.
+type PathElement struct {
+	Key   string // key of the object member
+	Index int    // index of the array element, or -1 for an object member
+}
+
+func (e PathElement) IsIndex() bool {
+	return e.Index >= 0
+}
+
+// FormatPath formats elems like orders[3].items[0].price.
+// A key that can't be written after a dot is quoted like ["a.b"].
+func FormatPath(elems []PathElement) string {
+	var b []byte
+	for _, elem := range elems {
+		switch {
+		case elem.IsIndex():
+			b = append(b, '[')
+			b = strconv.AppendInt(b, int64(elem.Index), 10)
+			b = append(b, ']')
+		case isPlainPathKey(elem.Key):
+			if len(b) > 0 {
+				b = append(b, '.')
+			}
+			b = append(b, elem.Key...)
+		default:
+			b = append(b, '[')
+			b = strconv.AppendQuote(b, elem.Key)
+			b = append(b, ']')
+		}
+	}
+	return string(b)
+}
+
+func isPlainPathKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		switch key[i] {
+		case '.', '[', ']', '"', '\\', ' ':
+			return false
+		}
+	}
+	return true
+}
+
+// PrependPath adds elem to the beginning of the path of err.
+// err is returned as is if it doesn't have a path.
+//
+// The decoders call PrependPath for every level while unwinding, so the elements are kept leaf first
+// and Path and PathElements are not updated until FinishPath is called.
+func PrependPath(err error, elem PathElement) error {
+	if leaf := leafPathOf(err); leaf != nil {
+		*leaf = append(*leaf, elem)
+	}
+	return err
+}
+
+// PrependPathElements adds elems to the beginning of the path of err in the same way as PrependPath.
+func PrependPathElements(err error, elems []PathElement) error {
+	for i := len(elems) - 1; i >= 0; i-- {
+		PrependPath(err, elems[i])
+	}
+	return err
+}
+
+// TrimPathRoot removes the first element of the path of err added by PrependPath and returns it.
+// It returns false if err doesn't have such an element.
+func TrimPathRoot(err error) (PathElement, bool) {
+	leaf := leafPathOf(err)
+	if leaf == nil || len(*leaf) == 0 {
+		return PathElement{}, false
+	}
+	root := (*leaf)[len(*leaf)-1]
+	*leaf = (*leaf)[:len(*leaf)-1]
+	return root, true
+}
+
+func leafPathOf(err error) *[]PathElement {
+	switch e := err.(type) {
+	case *UnmarshalTypeError:
+		return &e.leafPath
+	case *SyntaxError:
+		return &e.leafPath
+	case *MissingFieldError:
+		return &e.leafPath
+	}
+	return nil
+}
+
+// FinishPath puts the elements added by PrependPath at the beginning of PathElements and formats Path.
+// The errors in DecodeErrors are finished one by one.
+func FinishPath(err error) error {
+	switch e := err.(type) {
+	case *UnmarshalTypeError:
+		e.PathElements, e.Path, e.leafPath = finishPath(e.leafPath, e.PathElements, e.Path)
+	case *SyntaxError:
+		e.PathElements, e.Path, e.leafPath = finishPath(e.leafPath, e.PathElements, e.Path)
+	case *MissingFieldError:
+		e.PathElements, e.Path, e.leafPath = finishPath(e.leafPath, e.PathElements, e.Path)
+	case DecodeErrors:
+		for _, collected := range e {
+			FinishPath(collected)
+		}
+	}
+	return err
+}
+
+func finishPath(leaf, elems []PathElement, path string) ([]PathElement, string, []PathElement) {
+	if len(leaf) == 0 {
+		return elems, path, nil
+	}
+	full := make([]PathElement, 0, len(leaf)+len(elems))
+	for i := len(leaf) - 1; i >= 0; i-- {
+		full = append(full, leaf[i])
+	}
+	full = append(full, elems...)
+	return full, FormatPath(full), nil
+}
+
 // An UnsupportedTypeError is returned by Marshal when attempting
 // to encode an unsupported value type.
 type UnsupportedTypeError struct {
@@ -139,6 +404,18 @@ func ErrUnexpectedEndOfJSON(msg string, cursor int64) *SyntaxError {
 	}
 }
 
+func ErrInvalidPath(msg, path string, offset int) *InvalidPathError {
+	return &InvalidPathError{msg: msg, Path: path, Offset: offset}
+}
+
+func ErrInvalidPointer(msg, pointer string, offset int) *InvalidPointerError {
+	return &InvalidPointerError{msg: msg, Pointer: pointer, Offset: offset}
+}
+
+func ErrPointerNotFound(pointer, token string) *PointerNotFoundError {
+	return &PointerNotFoundError{Pointer: pointer, Token: token}
+}
+
 func ErrExpected(msg string, cursor int64) *SyntaxError {
 	return &SyntaxError{msg: fmt.Sprintf("expected %s", msg), Offset: cursor}
 }
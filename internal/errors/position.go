@@ -0,0 +1,101 @@
+package errors
+
+import (
+	"bytes"
+)
+
+// maxSourceLineLen is the maximum length of the line kept to render an error.
+// A longer line is cut around the column of the error.
+const maxSourceLineLen = 80
+
+// Source is the part of the JSON input that is still available to locate the offset of an error.
+type Source struct {
+	Buf       []byte // the input that begins at offset Base
+	Base      int64  // offset of Buf[0] in the whole input
+	Line      int    // 1-based line of Buf[0]
+	LineStart int64  // offset of the beginning of the line that contains Buf[0]
+}
+
+// Position is the location of an offset in the JSON input.
+type Position struct {
+	Line   int // 1-based line
+	Column int // 1-based column in bytes
+
+	text  string // the line, which is cut around the column if it is long
+	caret int    // 0-based index of the offset in text
+}
+
+// Offset returns the offset of err in the JSON input.
+// It returns false unless err is *SyntaxError, *UnmarshalTypeError or *MissingFieldError.
+func Offset(err error) (int64, bool) {
+	switch e := err.(type) {
+	case *SyntaxError:
+		return e.Offset, true
+	case *UnmarshalTypeError:
+		return e.Offset, true
+	case *MissingFieldError:
+		return e.Offset, true
+	}
+	return 0, false
+}
+
+// Locate returns the position of offset in src.
+// It returns false if the offset is out of src, e.g. it has already been discarded from the buffer of a stream.
+func (src Source) Locate(offset int64) (Position, bool) {
+	rel := offset - src.Base
+	if rel < 0 || rel > int64(len(src.Buf)) {
+		return Position{}, false
+	}
+	before := src.Buf[:rel]
+	line := src.Line + bytes.Count(before, []byte{'\n'})
+	start := bytes.LastIndexByte(before, '\n') + 1
+	lineStart := src.LineStart
+	if start > 0 {
+		lineStart = src.Base + int64(start)
+	}
+	end := bytes.IndexByte(src.Buf[rel:], '\n')
+	if end < 0 {
+		end = len(src.Buf)
+	} else {
+		end += int(rel)
+	}
+	text := bytes.TrimRight(src.Buf[start:end], "\r")
+	caret := int(rel) - start
+	if len(text) > maxSourceLineLen {
+		from := caret - maxSourceLineLen/2
+		if from < 0 {
+			from = 0
+		}
+		to := from + maxSourceLineLen
+		if to > len(text) {
+			to = len(text)
+			from = to - maxSourceLineLen
+		}
+		text = text[from:to]
+		caret -= from
+	}
+	return Position{
+		Line:   line,
+		Column: int(offset-lineStart) + 1,
+		text:   string(text),
+		caret:  caret,
+	}, true
+}
+
+// Format returns the line of the position followed by a line
+// that has a caret under the column, like
+//
+//	"price": 1x,
+//	          ^
+func (p Position) Format() string {
+	caret := make([]byte, 0, p.caret+1)
+	for i := 0; i < p.caret; i++ {
+		if i < len(p.text) && p.text[i] == '\t' {
+			caret = append(caret, '\t')
+		} else {
+			caret = append(caret, ' ')
+		}
+	}
+	caret = append(caret, '^')
+	return p.text + "\n" + string(caret)
+}
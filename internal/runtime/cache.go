@@ -0,0 +1,42 @@
+package runtime
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// configCacheSize is the maximum number of entries of ConfigCache.
+const configCacheSize = 1024
+
+// ConfigCache caches the encoders or decoders compiled for the configurations given by callers.
+// The configurations may be created for each call ( e.g. the fields selected by a request ),
+// so the cache is cleared when it is full instead of growing without bound.
+// The zero value is an empty cache.
+type ConfigCache struct {
+	mu      sync.Mutex
+	entries atomic.Value // *sync.Map
+	count   int
+}
+
+// Load returns the value cached for key.
+func (c *ConfigCache) Load(key interface{}) (interface{}, bool) {
+	entries, _ := c.entries.Load().(*sync.Map)
+	if entries == nil {
+		return nil, false
+	}
+	return entries.Load(key)
+}
+
+// Store caches value for key.
+func (c *ConfigCache) Store(key, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entries, _ := c.entries.Load().(*sync.Map)
+	if entries == nil || c.count >= configCacheSize {
+		entries = &sync.Map{}
+		c.entries.Store(entries)
+		c.count = 0
+	}
+	entries.Store(key, value)
+	c.count++
+}
@@ -0,0 +1,10 @@
+package runtime
+
+// MapItem is an entry of MapSlice.
+type MapItem struct {
+	Key   string
+	Value interface{}
+}
+
+// MapSlice is a JSON object that keeps the order of its keys.
+type MapSlice []MapItem
@@ -0,0 +1,83 @@
+package runtime
+
+import (
+	"strings"
+	"unicode"
+)
+
+// NamingStrategy converts the name of a struct field that has no key in the tag
+// into the key of a JSON object.
+type NamingStrategy struct {
+	convert func(string) string
+}
+
+func NewNamingStrategy(convert func(string) string) *NamingStrategy {
+	return &NamingStrategy{convert: convert}
+}
+
+// Key returns the JSON object key for the struct field name.
+// A nil *NamingStrategy returns name as is.
+func (s *NamingStrategy) Key(name string) string {
+	if s == nil {
+		return name
+	}
+	return s.convert(name)
+}
+
+// splitWords splits a Go identifier into words.
+// e.g.) "UserID" => ["User", "ID"], "HTTPServer" => ["HTTP", "Server"], "user_name" => ["user", "name"]
+func splitWords(name string) []string {
+	var (
+		words []string
+		start int
+	)
+	runes := []rune(name)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if r == '_' || r == '-' {
+			if start < i {
+				words = append(words, string(runes[start:i]))
+			}
+			start = i + 1
+			continue
+		}
+		if i == start || !unicode.IsUpper(r) {
+			continue
+		}
+		prev := runes[i-1]
+		if unicode.IsLower(prev) || unicode.IsDigit(prev) ||
+			(unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	if start < len(runes) {
+		words = append(words, string(runes[start:]))
+	}
+	return words
+}
+
+// ToSnakeCase converts name to snake_case. e.g.) "UserID" => "user_id"
+func ToSnakeCase(name string) string {
+	return strings.ToLower(strings.Join(splitWords(name), "_"))
+}
+
+// ToKebabCase converts name to kebab-case. e.g.) "UserID" => "user-id"
+func ToKebabCase(name string) string {
+	return strings.ToLower(strings.Join(splitWords(name), "-"))
+}
+
+// ToCamelCase converts name to camelCase. e.g.) "UserID" => "userID"
+func ToCamelCase(name string) string {
+	words := splitWords(name)
+	if len(words) == 0 {
+		return name
+	}
+	words[0] = strings.ToLower(words[0])
+	for i := 1; i < len(words); i++ {
+		runes := []rune(words[i])
+		runes[0] = unicode.ToUpper(runes[0])
+		words[i] = string(runes)
+	}
+	return strings.Join(words, "")
+}
@@ -6,11 +6,19 @@ import (
 	"unicode"
 )
 
-func getTag(field reflect.StructField) string {
-	return field.Tag.Get("json")
+// DefaultTagKey is the key of the struct tag that is read when no other key is configured.
+const DefaultTagKey = "json"
+
+func getTag(field reflect.StructField, tagKey string) string {
+	if tagKey == "" {
+		tagKey = DefaultTagKey
+	}
+	return field.Tag.Get(tagKey)
 }
 
-func IsIgnoredStructField(field reflect.StructField) bool {
+// IsIgnoredStructField reports whether field is not encoded or decoded.
+// The tag of field is read with tagKey, or the json key if tagKey is empty.
+func IsIgnoredStructField(field reflect.StructField, tagKey string) bool {
 	if field.PkgPath != "" {
 		if field.Anonymous {
 			if !(field.Type.Kind() == reflect.Ptr && field.Type.Elem().Kind() == reflect.Struct) && field.Type.Kind() != reflect.Struct {
@@ -21,7 +29,7 @@ func IsIgnoredStructField(field reflect.StructField) bool {
 			return true
 		}
 	}
-	tag := getTag(field)
+	tag := getTag(field, tagKey)
 	return tag == "-"
 }
 
@@ -29,7 +37,12 @@ type StructTag struct {
 	Key         string
 	IsTaggedKey bool
 	IsOmitEmpty bool
+	IsOmitZero  bool
 	IsString    bool
+	IsInline    bool
+	IsRequired  bool
+	HasDefault  bool
+	Default     string // the literal of the default value
 	Field       reflect.StructField
 }
 
@@ -61,9 +74,11 @@ func isValidTag(s string) bool {
 	return true
 }
 
-func StructTagFromField(field reflect.StructField) *StructTag {
+// StructTagFromField parses the tag of field read with tagKey, or the json key if tagKey is empty.
+// If the tag doesn't have a key, the key is created from the field name by naming.
+func StructTagFromField(field reflect.StructField, tagKey string, naming *NamingStrategy) *StructTag {
 	keyName := field.Name
-	tag := getTag(field)
+	tag := getTag(field, tagKey)
 	st := &StructTag{Field: field}
 	opts := strings.Split(tag, ",")
 	if len(opts) > 0 {
@@ -72,10 +87,41 @@ func StructTagFromField(field reflect.StructField) *StructTag {
 			st.IsTaggedKey = true
 		}
 	}
+	if !st.IsTaggedKey {
+		keyName = naming.Key(keyName)
+	}
 	st.Key = keyName
+	st.Default, st.HasDefault = field.Tag.Lookup("default")
 	if len(opts) > 1 {
-		st.IsOmitEmpty = opts[1] == "omitempty"
-		st.IsString = opts[1] == "string"
+		for i, opt := range opts[1:] {
+			if strings.HasPrefix(opt, defaultOptPrefix) {
+				// the literal may contain commas, so it takes the rest of the tag
+				st.Default = strings.TrimPrefix(strings.Join(opts[i+1:], ","), defaultOptPrefix)
+				st.HasDefault = true
+				break
+			}
+			switch opt {
+			case "omitempty":
+				st.IsOmitEmpty = true
+			case "string":
+				st.IsString = true
+			case "omitzero":
+				st.IsOmitZero = true
+			case "inline", "unknown":
+				st.IsInline = isInlineMapType(field.Type)
+			case "required":
+				st.IsRequired = true
+			}
+		}
 	}
 	return st
 }
+
+// defaultOptPrefix is the prefix of the option that has the default value of the field.
+// The option must be the last one because the rest of the tag is the literal.
+const defaultOptPrefix = "default="
+
+// isInlineMapType reports whether typ can keep the unknown keys of an object by the inline option.
+func isInlineMapType(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Map && typ.Key().Kind() == reflect.String
+}
@@ -0,0 +1,31 @@
+package runtime
+
+import (
+	"sync"
+)
+
+// Union is an interface type whose values are distinguished by the value of a key of JSON objects.
+type Union struct {
+	Key   string
+	Types map[string]*Type // concrete types by the value of Key
+	Names map[*Type]string // values of Key by the concrete type
+}
+
+var (
+	unionsMu sync.RWMutex
+	unions   = map[*Type]*Union{}
+)
+
+// RegisterUnion registers u as the union of the interface type typ.
+func RegisterUnion(typ *Type, u *Union) {
+	unionsMu.Lock()
+	defer unionsMu.Unlock()
+	unions[typ] = u
+}
+
+// LookupUnion returns the union registered for the interface type typ.
+func LookupUnion(typ *Type) *Union {
+	unionsMu.RLock()
+	defer unionsMu.RUnlock()
+	return unions[typ]
+}
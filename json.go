@@ -2,7 +2,9 @@ package json
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"reflect"
 
 	"github.com/goccy/go-json/internal/encoder"
 )
@@ -13,6 +15,12 @@ type Marshaler interface {
 	MarshalJSON() ([]byte, error)
 }
 
+// MarshalerContext is the interface implemented by types that
+// can marshal themselves into valid JSON with context.Context.
+type MarshalerContext interface {
+	MarshalJSON(context.Context) ([]byte, error)
+}
+
 // Unmarshaler is the interface implemented by types
 // that can unmarshal a JSON description of themselves.
 // The input can be assumed to be a valid encoding of
@@ -25,6 +33,12 @@ type Unmarshaler interface {
 	UnmarshalJSON([]byte) error
 }
 
+// UnmarshalerContext is the interface implemented by types
+// that can unmarshal with context.Context a JSON description of themselves.
+type UnmarshalerContext interface {
+	UnmarshalJSON(context.Context, []byte) error
+}
+
 // Marshal returns the JSON encoding of v.
 //
 // Marshal traverses the value v recursively.
@@ -62,6 +76,7 @@ type Unmarshaler interface {
 //
 // The encoding of each struct field can be customized by the format string
 // stored under the "json" key in the struct field's tag.
+// Another key can be read instead by the EncodeTagKey and DecodeTagKey options.
 // The format string gives the name of the field, possibly followed by a
 // comma-separated list of options. The name may be empty in order to
 // specify options without overriding the default field name.
@@ -71,6 +86,14 @@ type Unmarshaler interface {
 // false, 0, a nil pointer, a nil interface value, and any empty array,
 // slice, map, or string.
 //
+// The "omitzero" option specifies that the field should be omitted
+// from the encoding if the field has the zero value of its type ( e.g. time.Time{} ).
+// If the field type has an "IsZero() bool" method, it is used to determine
+// whether the value is zero instead. "omitempty" and "omitzero" can be
+// specified together, and then the field is omitted if it is empty or zero.
+// Like reflect.Value.IsZero, a floating point -0 is not the zero value, so it is
+// omitted by "omitempty" but not by "omitzero".
+//
 // As a special case, if the field tag is "-", the field is always omitted.
 // Note that a field with name "-" can still be generated using the tag "-,".
 //
@@ -102,6 +125,37 @@ type Unmarshaler interface {
 //
 //    Int64String int64 `json:",string"`
 //
+// The "inline" option, or its alias "unknown", keeps the object keys that don't
+// have a corresponding struct field. It applies only to a field of map type
+// that has string keys, such as map[string]RawMessage or map[string]interface{}.
+// Unmarshal stores the unknown keys and their values in the map, and Marshal
+// appends the entries of the map to the object after the other fields.
+// Marshal drops the entries that have the keys of the other fields, unless
+// the map is encoded by its own MarshalJSON method:
+//
+//    Extra map[string]json.RawMessage `json:",inline"`
+//
+// The "required" option is used by Unmarshal. If an object doesn't have the key
+// of a field with the option, Unmarshal returns a MissingFieldError that lists
+// all the absent keys of the object after decoding it. The key is present even
+// if its value is null:
+//
+//    ID int `json:"id,required"`
+//
+// The default value of a field is given by the "default" tag or the "default="
+// option, which must be the last option because the rest of the tag is the value.
+// If an object doesn't have the key of the field, Unmarshal sets the default value
+// to the field. The value of a string field is the string itself, and the value
+// of other fields is a JSON literal:
+//
+//    Host string   `json:"host" default:"localhost"`
+//    Port int      `json:"port,default=8080"`
+//    Tags []string `json:"tags,default=[\"a\",\"b\"]"`
+//
+// The literal is decoded once when the decoder of the struct is compiled, and each
+// decoded object gets a copy of the value. A default value that contains the field
+// itself ( e.g. the default object of a pointer to the struct ) is reported as an error.
+//
 // The key name will be used if it's a non-empty string consisting of
 // only Unicode letters, digits, and ASCII punctuation except quotation
 // marks, backslash, and comma.
@@ -145,6 +199,8 @@ type Unmarshaler interface {
 //
 // Interface values encode as the value contained in the interface.
 // A nil interface value encodes as the null JSON value.
+// For an interface registered by RegisterUnion, the object encoded from a struct
+// begins with the discriminator key of the concrete type.
 //
 // Channel, complex, and function values cannot be encoded in JSON.
 // Attempting to encode such a value causes Marshal to return
@@ -158,18 +214,20 @@ func Marshal(v interface{}) ([]byte, error) {
 	return MarshalWithOption(v)
 }
 
+// MarshalContext returns the JSON encoding of v with context.Context and EncodeOption.
+// The context is passed to the MarshalJSON method of values implementing MarshalerContext.
+func MarshalContext(ctx context.Context, v interface{}, optFuncs ...EncodeOptionFunc) ([]byte, error) {
+	return marshalContext(ctx, v, EncodeOption{Flag: EncodeOptionHTMLEscape}.apply(optFuncs))
+}
+
 // MarshalNoEscape
 func MarshalNoEscape(v interface{}) ([]byte, error) {
-	return marshalNoEscape(v, EncodeOptionHTMLEscape)
+	return marshalNoEscape(v, EncodeOption{Flag: EncodeOptionHTMLEscape})
 }
 
 // MarshalWithOption returns the JSON encoding of v with EncodeOption.
 func MarshalWithOption(v interface{}, optFuncs ...EncodeOptionFunc) ([]byte, error) {
-	opt := EncodeOptionHTMLEscape
-	for _, optFunc := range optFuncs {
-		opt = optFunc(opt)
-	}
-	return marshal(v, opt)
+	return marshal(v, EncodeOption{Flag: EncodeOptionHTMLEscape}.apply(optFuncs))
 }
 
 // MarshalIndent is like Marshal but applies Indent to format the output.
@@ -181,11 +239,19 @@ func MarshalIndent(v interface{}, prefix, indent string) ([]byte, error) {
 
 // MarshalIndentWithOption is like Marshal but applies Indent to format the output with EncodeOption.
 func MarshalIndentWithOption(v interface{}, prefix, indent string, optFuncs ...EncodeOptionFunc) ([]byte, error) {
-	opt := EncodeOptionHTMLEscape | EncodeOptionIndent
-	for _, optFunc := range optFuncs {
-		opt = optFunc(opt)
-	}
-	return marshalIndent(v, prefix, indent, opt)
+	return marshalIndent(v, prefix, indent, EncodeOption{Flag: EncodeOptionHTMLEscape | EncodeOptionIndent}.apply(optFuncs))
+}
+
+// RegisterTypeEncoder registers fn as the encoder of typ.
+// fn appends the JSON encoding of v, whose dynamic type is typ, to b and returns the extended buffer.
+// It is checked before the Marshaler interface, and unlike MarshalJSON the result is neither compacted nor validated,
+// so fn must append valid JSON.
+//
+// RegisterTypeEncoder discards the compiled encoders, so that fn is used for the types that have already been encoded.
+// The encoders are compiled again after that, so call it before encoding ( e.g. in init ).
+// Use Encoder.RegisterTypeEncoder to apply an encoder to a single Encoder.
+func RegisterTypeEncoder(typ reflect.Type, fn func(b []byte, v interface{}) ([]byte, error)) {
+	encoder.RegisterTypeEncoder(typ, fn)
 }
 
 // Unmarshal parses the JSON-encoded data and stores the result
@@ -213,7 +279,11 @@ func MarshalIndentWithOption(v interface{}, prefix, indent string, optFuncs ...E
 // keys to the keys used by Marshal (either the struct field name or its tag),
 // preferring an exact match but also accepting a case-insensitive match. By
 // default, object keys which don't have a corresponding struct field are
-// ignored (see Decoder.DisallowUnknownFields for an alternative).
+// ignored (see Decoder.DisallowUnknownFields for an alternative), or stored
+// in the field that has the "inline" option if the struct has one.
+// If the object doesn't have the keys of the fields that have the "required"
+// option, Unmarshal returns a MissingFieldError. The default values given by
+// the "default" tag are set to the other fields whose keys are absent.
 //
 // To unmarshal JSON into an interface value,
 // Unmarshal stores one of these in the interface value:
@@ -225,6 +295,12 @@ func MarshalIndentWithOption(v interface{}, prefix, indent string, optFuncs ...E
 //	map[string]interface{}, for JSON objects
 //	nil for JSON null
 //
+// The UseInt64 option stores int64, or uint64 if it overflows int64, for the JSON
+// numbers that are integers. The UseMapSlice option stores MapSlice for JSON objects
+// instead, keeping the order of the keys.
+// For an interface registered by RegisterUnion, Unmarshal stores a value of the
+// concrete type chosen by the discriminator key of the JSON object.
+//
 // To unmarshal a JSON array into a slice, Unmarshal resets the slice length
 // to zero and then appends each element to the slice.
 // As a special case, to unmarshal an empty JSON array into a slice,
@@ -244,6 +320,10 @@ func MarshalIndentWithOption(v interface{}, prefix, indent string, optFuncs ...E
 // either be any string type, an integer, implement json.Unmarshaler, or
 // implement encoding.TextUnmarshaler.
 //
+// To unmarshal a JSON object into a MapSlice, Unmarshal resets the slice length
+// to zero and then appends each member of the object in the order of the document,
+// keeping duplicate keys.
+//
 // If a JSON value is not appropriate for a given target type,
 // or if a JSON number overflows the target type, Unmarshal
 // skips that field and completes the unmarshaling as best it can.
@@ -251,6 +331,8 @@ func MarshalIndentWithOption(v interface{}, prefix, indent string, optFuncs ...E
 // an UnmarshalTypeError describing the earliest such error. In any
 // case, it's not guaranteed that all the remaining fields following
 // the problematic one will be unmarshaled into the target object.
+// Use UnmarshalWithOption with CollectErrors to go on decoding
+// after such errors and get all of them as DecodeErrors.
 //
 // The JSON null value unmarshals into an interface, map, pointer, or slice
 // by setting that Go value to nil. Because null is often used in JSON to mean
@@ -266,8 +348,68 @@ func Unmarshal(data []byte, v interface{}) error {
 	return unmarshal(data, v)
 }
 
-func UnmarshalNoEscape(data []byte, v interface{}) error {
-	return unmarshalNoEscape(data, v)
+// UnmarshalWithOption is like Unmarshal but applies DecodeOption.
+func UnmarshalWithOption(data []byte, v interface{}, optFuncs ...DecodeOptionFunc) error {
+	return unmarshal(data, v, optFuncs...)
+}
+
+// UnmarshalPath parses the JSON-encoded data and stores the values selected by
+// the JSON Path expression path in the value pointed to by v.
+// Values that are not selected are skipped without being decoded.
+// See CreatePath for the supported syntax and Path.Unmarshal for how the selected values are stored.
+func UnmarshalPath(data []byte, path string, v interface{}, optFuncs ...DecodeOptionFunc) error {
+	p, err := CreatePath(path)
+	if err != nil {
+		return err
+	}
+	return p.Unmarshal(data, v, optFuncs...)
+}
+
+// GetPointer returns a copy of the JSON-encoded value referenced by the JSON Pointer ( RFC 6901 ) pointer.
+// Values that are not on the way to the referenced value are skipped without being decoded.
+func GetPointer(data []byte, pointer string) (RawMessage, error) {
+	p, err := ParsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	return p.Get(data)
+}
+
+// UnmarshalPointer parses the JSON-encoded data and stores the value referenced by
+// the JSON Pointer ( RFC 6901 ) pointer in the value pointed to by v.
+func UnmarshalPointer(data []byte, pointer string, v interface{}, optFuncs ...DecodeOptionFunc) error {
+	p, err := ParsePointer(pointer)
+	if err != nil {
+		return err
+	}
+	return p.Unmarshal(data, v, optFuncs...)
+}
+
+// UnmarshalContext parses the JSON-encoded data and stores the result
+// in the value pointed to by v with context.Context.
+// The context is passed to the UnmarshalJSON method of values implementing
+// UnmarshalerContext.
+func UnmarshalContext(ctx context.Context, data []byte, v interface{}, optFuncs ...DecodeOptionFunc) error {
+	return unmarshalContext(ctx, data, v, optFuncs...)
+}
+
+func UnmarshalNoEscape(data []byte, v interface{}, optFuncs ...DecodeOptionFunc) error {
+	return unmarshalNoEscape(data, v, optFuncs...)
+}
+
+// UnmarshalBorrow is like Unmarshal, but the decoded values borrow data instead of copying it:
+// strings without escape sequences and RawMessage values point into data.
+// The caller must not modify data while the decoded values are in use. data is not modified by UnmarshalBorrow.
+//
+// The decoder needs a nul byte after the end of the input. To decode data without copying it,
+// put a nul byte after the end of data within its capacity:
+//
+//	buf = append(buf, 0)
+//	err := json.UnmarshalBorrow(buf[:len(buf)-1], &v)
+//
+// Otherwise data is copied once, and the values point into the copy.
+func UnmarshalBorrow(data []byte, v interface{}, optFuncs ...DecodeOptionFunc) error {
+	return unmarshalBorrow(data, v, optFuncs...)
 }
 
 // A Token holds a value of one of these types:
@@ -326,7 +468,7 @@ func HTMLEscape(dst *bytes.Buffer, src []byte) {
 	if err := dec.Decode(&v); err != nil {
 		return
 	}
-	buf, _ := marshal(v, EncodeOptionHTMLEscape)
+	buf, _ := marshal(v, EncodeOption{Flag: EncodeOptionHTMLEscape})
 	dst.Write(buf)
 }
 
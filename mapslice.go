@@ -0,0 +1,18 @@
+package json
+
+import "github.com/goccy/go-json/internal/runtime"
+
+// MapItem is a key-value pair of MapSlice.
+type MapItem = runtime.MapItem
+
+// MapSlice is a JSON object that keeps the order of its keys, unlike a Go map.
+//
+// Marshal encodes the items of a MapSlice in the order of the slice,
+// and Unmarshal fills a MapSlice with the members of an object in the order of the document.
+// The values are encoded and decoded in the same way as the values of map[string]interface{},
+// except that the nested objects are decoded into MapSlice as well to keep the order of their keys.
+// A nil MapSlice is encoded as null.
+//
+//	v := json.MapSlice{{Key: "b", Value: 1}, {Key: "a", Value: 2}}
+//	b, _ := json.Marshal(v) // {"b":1,"a":2}
+type MapSlice = runtime.MapSlice
@@ -0,0 +1,28 @@
+package json
+
+import "github.com/goccy/go-json/internal/runtime"
+
+// NamingStrategy converts the name of a struct field that has no key in the json tag
+// into the key of a JSON object.
+//
+// Encoders and decoders are compiled and cached for each NamingStrategy,
+// so create a NamingStrategy once and reuse it.
+// The caches have a limited size, so NamingStrategies created for each call
+// are compiled again instead of being kept forever.
+type NamingStrategy = runtime.NamingStrategy
+
+// NewNamingStrategy creates a NamingStrategy that converts a field name with convert.
+func NewNamingStrategy(convert func(string) string) *NamingStrategy {
+	return runtime.NewNamingStrategy(convert)
+}
+
+var (
+	// SnakeCase converts a field name to snake_case. e.g.) UserID => user_id
+	SnakeCase = NewNamingStrategy(runtime.ToSnakeCase)
+
+	// CamelCase converts a field name to camelCase. e.g.) UserID => userID
+	CamelCase = NewNamingStrategy(runtime.ToCamelCase)
+
+	// KebabCase converts a field name to kebab-case. e.g.) UserID => user-id
+	KebabCase = NewNamingStrategy(runtime.ToKebabCase)
+)
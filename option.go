@@ -1,15 +1,134 @@
 package json
 
-type EncodeOptionFunc func(EncodeOption) EncodeOption
+import (
+	"github.com/goccy/go-json/internal/encoder"
+)
 
-func UnorderedMap() func(EncodeOption) EncodeOption {
-	return func(opt EncodeOption) EncodeOption {
-		return opt | EncodeOptionUnorderedMap
+type EncodeOptionFunc func(*EncodeOption)
+
+func UnorderedMap() func(*EncodeOption) {
+	return func(opt *EncodeOption) {
+		opt.Flag |= EncodeOptionUnorderedMap
+	}
+}
+
+func Debug() func(*EncodeOption) {
+	return func(opt *EncodeOption) {
+		opt.Flag |= EncodeOptionDebug
+	}
+}
+
+// Fields causes the encoder to emit only the given struct fields and map keys.
+// Fields are specified by their JSON key, and a nested field is specified by joining
+// the keys with dots ( e.g. "owner.email" ). The fields of embedded structs are selected
+// as if they were fields of the outer struct.
+//
+// Selections apply through pointers, slices, arrays and interface values.
+// Only maps with string keys are filtered, and the selections below the keys of a map
+// are merged because they share the value type ( e.g. "m.a.x" and "m.b.y" encode x and y in both a and b ).
+func Fields(fields ...string) func(*EncodeOption) {
+	query := encoder.NewFieldQuery(fields)
+	return func(opt *EncodeOption) {
+		opt.config.Fields = query
+	}
+}
+
+// EncodeFieldNaming causes the encoder to create the keys of struct fields
+// that have no key in the json tag with naming.
+func EncodeFieldNaming(naming *NamingStrategy) func(*EncodeOption) {
+	return func(opt *EncodeOption) {
+		opt.config.Naming = naming
+	}
+}
+
+// EncodeTagKey causes the encoder to read the struct tags with key instead of "json"
+// ( e.g. `api:"name,omitempty"` for EncodeTagKey("api") ).
+// The options of the tag are the same as the json tag.
+// A field without the tag is encoded by its name like a field without the json tag.
+// Encoders are compiled and cached for each key.
+func EncodeTagKey(key string) func(*EncodeOption) {
+	return func(opt *EncodeOption) {
+		opt.config.TagKey = key
+	}
+}
+
+type DecodeOptionFunc func(*DecodeOption)
+
+// UseNumber causes the decoder to unmarshal a number into an interface{} as a
+// Number instead of as a float64.
+func UseNumber() func(*DecodeOption) {
+	return func(opt *DecodeOption) {
+		opt.Flag |= DecodeOptionUseNumber
+	}
+}
+
+// UseInt64 causes the decoder to unmarshal an integer into an interface{} as an
+// int64, or as a uint64 if it overflows int64. The other numbers are unmarshaled
+// as a float64, or as a Number with UseNumber.
+func UseInt64() func(*DecodeOption) {
+	return func(opt *DecodeOption) {
+		opt.Flag |= DecodeOptionUseInt64
+	}
+}
+
+// UseMapSlice causes the decoder to unmarshal an object into an interface{} as a
+// MapSlice instead of as a map[string]interface{}, keeping the order of the keys.
+// It applies to the objects at all nesting levels, including the objects in a []interface{}.
+func UseMapSlice() func(*DecodeOption) {
+	return func(opt *DecodeOption) {
+		opt.Flag |= DecodeOptionUseMapSlice
+	}
+}
+
+// DisallowUnknownFields causes the decoder to return an error when the destination
+// is a struct and the input contains object keys which do not match any
+// non-ignored, exported fields in the destination.
+// The keys are still accepted by a struct that has a field with the "inline" option.
+func DisallowUnknownFields() func(*DecodeOption) {
+	return func(opt *DecodeOption) {
+		opt.Flag |= DecodeOptionDisallowUnknownFields
+	}
+}
+
+// DecodeFieldNaming causes the decoder to match object keys against the keys created with naming
+// for struct fields that have no key in the json tag.
+func DecodeFieldNaming(naming *NamingStrategy) func(*DecodeOption) {
+	return func(opt *DecodeOption) {
+		opt.config.naming = naming
+	}
+}
+
+// DecodeTagKey causes the decoder to read the struct tags with key instead of "json".
+// The options of the tag are the same as the json tag.
+// Decoders are compiled and cached for each key.
+func DecodeTagKey(key string) func(*DecodeOption) {
+	return func(opt *DecodeOption) {
+		opt.config.tagKey = key
+	}
+}
+
+// DecodeCaseSensitiveKeys causes the decoder to match object keys to struct fields case-sensitively.
+// By default, like encoding/json, a key that differs only in case ( e.g. "ID" and "id" ) matches the same field.
+func DecodeCaseSensitiveKeys() func(*DecodeOption) {
+	return func(opt *DecodeOption) {
+		opt.config.caseSensitive = true
+	}
+}
+
+// DecodeWithLimits causes the decoder to return an error when the input exceeds limits.
+func DecodeWithLimits(limits DecodeLimits) func(*DecodeOption) {
+	return func(opt *DecodeOption) {
+		opt.limits = &limits
 	}
 }
 
-func Debug() func(EncodeOption) EncodeOption {
-	return func(opt EncodeOption) EncodeOption {
-		return opt | EncodeOptionDebug
+// CollectErrors causes the decoder to go on decoding after a JSON value that is not appropriate
+// for the Go type, skipping the value and leaving the destination unchanged.
+// The type errors and the missing required fields are returned together as DecodeErrors,
+// each with the offset and the path of the value.
+// Other errors ( e.g. syntax errors ) stop decoding and are returned as is.
+func CollectErrors() func(*DecodeOption) {
+	return func(opt *DecodeOption) {
+		opt.Flag |= DecodeOptionCollectErrors
 	}
 }
@@ -0,0 +1,354 @@
+package json
+
+import (
+	"fmt"
+	"strconv"
+	"unsafe"
+)
+
+type pathSelectorType int
+
+const (
+	pathSelectorKey pathSelectorType = iota
+	pathSelectorIndex
+	pathSelectorWildcard
+)
+
+type pathSelector struct {
+	typ   pathSelectorType
+	key   string
+	index int
+}
+
+// pathWalkFunc is called by Path.walk with the cursor, the depth and the path of a selected value,
+// and returns the cursor after the value.
+type pathWalkFunc func(cursor, depth int64, path []PathElement) (int64, error)
+
+// Path is a compiled JSON Path expression.
+// A Path can be reused to decode many documents and is safe for concurrent use.
+type Path struct {
+	src        string
+	selectors  []pathSelector
+	isWildcard bool // whether the path can select multiple values
+}
+
+var (
+	pathKeyDecoder = newStringDecoder("", "")
+)
+
+// CreatePath compiles a JSON Path expression.
+//
+// The following subset of JSON Path is supported:
+//
+//	$          the root value
+//	.name      the member "name" of an object
+//	['name']   the member "name" of an object ( name may contain any character )
+//	[0]        the element at index 0 of an array
+//	.* or [*]  every member of an object or every element of an array
+func CreatePath(p string) (*Path, error) {
+	if len(p) == 0 || p[0] != '$' {
+		return nil, errInvalidPath("path must start with $", p, 0)
+	}
+	path := &Path{src: p}
+	cursor := 1
+	for cursor < len(p) {
+		switch p[cursor] {
+		case '.':
+			cursor++
+			if cursor >= len(p) {
+				return nil, errInvalidPath("expected key after .", p, cursor)
+			}
+			switch p[cursor] {
+			case '.':
+				return nil, errInvalidPath("recursive descent is not supported", p, cursor)
+			case '*':
+				path.addSelector(pathSelector{typ: pathSelectorWildcard})
+				cursor++
+				continue
+			}
+			start := cursor
+			for cursor < len(p) && p[cursor] != '.' && p[cursor] != '[' && p[cursor] != ']' {
+				cursor++
+			}
+			if start == cursor {
+				return nil, errInvalidPath("expected key after .", p, cursor)
+			}
+			path.addSelector(pathSelector{typ: pathSelectorKey, key: p[start:cursor]})
+		case '[':
+			sel, c, err := parsePathBracket(p, cursor+1)
+			if err != nil {
+				return nil, err
+			}
+			path.addSelector(sel)
+			cursor = c
+		default:
+			return nil, errInvalidPath(fmt.Sprintf("unexpected character %q", p[cursor]), p, cursor)
+		}
+	}
+	return path, nil
+}
+
+func parsePathBracket(p string, cursor int) (pathSelector, int, error) {
+	if cursor >= len(p) {
+		return pathSelector{}, 0, errInvalidPath("unexpected end of path", p, cursor)
+	}
+	switch c := p[cursor]; {
+	case c == '*':
+		cursor++
+		if cursor >= len(p) || p[cursor] != ']' {
+			return pathSelector{}, 0, errInvalidPath("expected ]", p, cursor)
+		}
+		return pathSelector{typ: pathSelectorWildcard}, cursor + 1, nil
+	case c == '\'' || c == '"':
+		cursor++
+		key := []byte{}
+		for {
+			if cursor >= len(p) {
+				return pathSelector{}, 0, errInvalidPath("unterminated quoted key", p, cursor)
+			}
+			if p[cursor] == '\\' && cursor+1 < len(p) {
+				key = append(key, p[cursor+1])
+				cursor += 2
+				continue
+			}
+			if p[cursor] == c {
+				break
+			}
+			key = append(key, p[cursor])
+			cursor++
+		}
+		cursor++
+		if cursor >= len(p) || p[cursor] != ']' {
+			return pathSelector{}, 0, errInvalidPath("expected ]", p, cursor)
+		}
+		return pathSelector{typ: pathSelectorKey, key: string(key)}, cursor + 1, nil
+	case '0' <= c && c <= '9':
+		start := cursor
+		for cursor < len(p) && '0' <= p[cursor] && p[cursor] <= '9' {
+			cursor++
+		}
+		if cursor >= len(p) || p[cursor] != ']' {
+			return pathSelector{}, 0, errInvalidPath("expected ]", p, cursor)
+		}
+		index, err := strconv.Atoi(p[start:cursor])
+		if err != nil {
+			return pathSelector{}, 0, errInvalidPath("invalid index", p, start)
+		}
+		return pathSelector{typ: pathSelectorIndex, index: index}, cursor + 1, nil
+	}
+	return pathSelector{}, 0, errInvalidPath(fmt.Sprintf("unexpected character %q", p[cursor]), p, cursor)
+}
+
+func (p *Path) addSelector(sel pathSelector) {
+	if sel.typ == pathSelectorWildcard {
+		p.isWildcard = true
+	}
+	p.selectors = append(p.selectors, sel)
+}
+
+// String returns the source text of the path.
+func (p *Path) String() string {
+	return p.src
+}
+
+// Unmarshal parses the JSON-encoded data and stores the values selected by the path
+// in the value pointed to by v. Values that are not selected are skipped without being decoded.
+//
+// If the path doesn't contain a wildcard, the selected value is decoded into v
+// and v is left unchanged when nothing is selected.
+// Otherwise, the selected values are decoded as if they were the elements of a JSON array,
+// so v is typically a pointer to a slice.
+// In both cases, the errors report the offsets in data and the paths from the root of data.
+// The options are applied in the same way as Unmarshal, and the limits also apply to the values that are not selected.
+func (p *Path) Unmarshal(data []byte, v interface{}, optFuncs ...DecodeOptionFunc) error {
+	src := make([]byte, len(data)+1) // append nul byte to the end
+	copy(src, data)
+
+	header := (*emptyInterface)(unsafe.Pointer(&v))
+
+	if err := validateType(header.typ, uintptr(header.ptr)); err != nil {
+		return err
+	}
+	opt := DecodeOption{}.apply(optFuncs)
+	if err := opt.limits.checkBytes(int64(len(data))); err != nil {
+		return err
+	}
+	dec, err := decodeCompileToGetDecoderWithConfig(header.typ, opt.config)
+	if err != nil {
+		return err
+	}
+	rctx := takeRuntimeContext()
+	rctx.buf = src
+	rctx.option = opt
+	if p.isWildcard {
+		err = p.unmarshalAll(rctx, dec, header.ptr)
+	} else {
+		_, err = p.walk(rctx, 0, 0, 0, nil, func(cursor, depth int64, path []PathElement) (int64, error) {
+			n := len(rctx.errs)
+			cursor, err := dec.decode(rctx, cursor, depth, header.ptr)
+			for _, e := range rctx.errs[n:] {
+				prependErrorPathElements(e, path)
+			}
+			if err != nil {
+				return 0, prependErrorPathElements(err, path)
+			}
+			return cursor, nil
+		})
+	}
+	err = collectedErrors(opt.Flag, rctx.errs, err)
+	err = finishErrorPath(err)
+	releaseRuntimeContext(rctx)
+	return err
+}
+
+// pathMatch is a value selected by a path with a wildcard.
+type pathMatch struct {
+	start    int64         // offset of the value in the input
+	selected int64         // offset of the value in the array of the selected values
+	path     []PathElement // path from the root to the value
+}
+
+func (p *Path) unmarshalAll(ctx *runtimeContext, dec decoder, ptr unsafe.Pointer) error {
+	var matches []pathMatch
+	selected := []byte{'['}
+	if _, err := p.walk(ctx, 0, 0, 0, nil, func(cursor, depth int64, path []PathElement) (int64, error) {
+		buf := ctx.buf
+		cursor = skipWhiteSpace(buf, cursor)
+		end, err := skipValue(buf, cursor, depth)
+		if err != nil {
+			return 0, prependErrorPathElements(err, path)
+		}
+		if len(selected) > 1 {
+			selected = append(selected, ',')
+		}
+		matches = append(matches, pathMatch{
+			start:    cursor,
+			selected: int64(len(selected)),
+			path:     append([]PathElement(nil), path...),
+		})
+		selected = append(selected, buf[cursor:end]...)
+		return end, nil
+	}); err != nil {
+		return err
+	}
+	selected = append(selected, ']', nul)
+	rctx := &runtimeContext{buf: selected, option: ctx.option, ctx: ctx.ctx}
+	_, err := dec.decode(rctx, 0, 0, ptr)
+	for _, e := range rctx.errs {
+		ctx.errs = append(ctx.errs, rebaseMatchError(e, matches))
+	}
+	if err != nil {
+		return rebaseMatchError(err, matches)
+	}
+	return nil
+}
+
+// rebaseMatchError moves err of decoding the array of the selected values to the value in the input,
+// replacing the index in the array by the path of the value.
+func rebaseMatchError(err error, matches []pathMatch) error {
+	root, ok := trimErrorPathRoot(err)
+	if !ok || !root.IsIndex() || root.Index >= len(matches) {
+		return err
+	}
+	m := matches[root.Index]
+	addErrorOffset(err, m.start-m.selected)
+	return prependErrorPathElements(err, m.path)
+}
+
+// walk traverses the value at cursor and calls fn with the cursor and the path of every value selected by selectors[idx:].
+// path is the path from the root to the value at cursor. Values that are not selected are skipped.
+func (p *Path) walk(ctx *runtimeContext, cursor, depth int64, idx int, path []PathElement, fn pathWalkFunc) (int64, error) {
+	if idx == len(p.selectors) {
+		return fn(cursor, depth, path)
+	}
+	buf := ctx.buf
+	cursor = skipWhiteSpace(buf, cursor)
+	switch buf[cursor] {
+	case '{':
+		if p.selectors[idx].typ != pathSelectorIndex {
+			return p.walkObject(ctx, cursor, depth, idx, path, fn)
+		}
+	case '[':
+		if p.selectors[idx].typ != pathSelectorKey {
+			return p.walkArray(ctx, cursor, depth, idx, path, fn)
+		}
+	}
+	return skipValue(buf, cursor, depth)
+}
+
+func (p *Path) walkObject(ctx *runtimeContext, cursor, depth int64, idx int, path []PathElement, fn pathWalkFunc) (int64, error) {
+	buf := ctx.buf
+	depth++
+	if err := ctx.option.limits.checkDepth(depth, buf[cursor], cursor); err != nil {
+		return 0, err
+	}
+	sel := p.selectors[idx]
+	cursor = skipWhiteSpace(buf, cursor+1)
+	if buf[cursor] == '}' {
+		return cursor + 1, nil
+	}
+	for {
+		key, c, err := pathKeyDecoder.decodeByte(buf, cursor)
+		if err != nil {
+			return 0, err
+		}
+		cursor = skipWhiteSpace(buf, c)
+		if buf[cursor] != ':' {
+			return 0, errExpected("colon after object key", cursor)
+		}
+		cursor++
+		if sel.typ == pathSelectorWildcard || string(key) == sel.key {
+			c, err = p.walk(ctx, cursor, depth, idx+1, append(path, PathElement{Key: string(key), Index: -1}), fn)
+		} else {
+			c, err = skipValue(buf, cursor, depth)
+		}
+		if err != nil {
+			return 0, err
+		}
+		cursor = skipWhiteSpace(buf, c)
+		switch buf[cursor] {
+		case '}':
+			return cursor + 1, nil
+		case ',':
+			cursor++
+		default:
+			return 0, errExpected("comma after object element", cursor)
+		}
+	}
+}
+
+func (p *Path) walkArray(ctx *runtimeContext, cursor, depth int64, idx int, path []PathElement, fn pathWalkFunc) (int64, error) {
+	buf := ctx.buf
+	depth++
+	if err := ctx.option.limits.checkDepth(depth, buf[cursor], cursor); err != nil {
+		return 0, err
+	}
+	sel := p.selectors[idx]
+	cursor = skipWhiteSpace(buf, cursor+1)
+	if buf[cursor] == ']' {
+		return cursor + 1, nil
+	}
+	for i := 0; ; i++ {
+		var (
+			c   int64
+			err error
+		)
+		if sel.typ == pathSelectorWildcard || i == sel.index {
+			c, err = p.walk(ctx, cursor, depth, idx+1, append(path, PathElement{Index: i}), fn)
+		} else {
+			c, err = skipValue(buf, cursor, depth)
+		}
+		if err != nil {
+			return 0, err
+		}
+		cursor = skipWhiteSpace(buf, c)
+		switch buf[cursor] {
+		case ']':
+			return cursor + 1, nil
+		case ',':
+			cursor++
+		default:
+			return 0, errExpected("comma after array element", cursor)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package json_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+func TestUnmarshalPath(t *testing.T) {
+	src := []byte(`{
+  "name": "list",
+  "items": [
+    {"id": 1, "tags": ["a", "b"], "meta": {"x": [1, 2, {"y": null}]}},
+    {"id": 2, "tags": []},
+    {"tags": ["c"], "id": 3}
+  ],
+  "a.b": {"c\"d": "escaped"}
+}`)
+	t.Run("wildcard", func(t *testing.T) {
+		var ids []int
+		assertErr(t, json.UnmarshalPath(src, "$.items[*].id", &ids))
+		assertEq(t, "ids", "[1 2 3]", fmt.Sprint(ids))
+	})
+	t.Run("nested wildcard", func(t *testing.T) {
+		var tags []string
+		assertErr(t, json.UnmarshalPath(src, "$.items[*].tags[*]", &tags))
+		assertEq(t, "tags", "[a b c]", fmt.Sprint(tags))
+	})
+	t.Run("object wildcard", func(t *testing.T) {
+		var v []interface{}
+		assertErr(t, json.UnmarshalPath(src, "$.items[0].*", &v))
+		assertEq(t, "length", 3, len(v))
+	})
+	t.Run("index", func(t *testing.T) {
+		var id int
+		assertErr(t, json.UnmarshalPath(src, "$.items[2].id", &id))
+		assertEq(t, "id", 3, id)
+	})
+	t.Run("struct", func(t *testing.T) {
+		var item struct {
+			ID   int      `json:"id"`
+			Tags []string `json:"tags"`
+		}
+		assertErr(t, json.UnmarshalPath(src, "$['items'][0]", &item))
+		assertEq(t, "id", 1, item.ID)
+		assertEq(t, "tags", "[a b]", fmt.Sprint(item.Tags))
+	})
+	t.Run("quoted key", func(t *testing.T) {
+		var v string
+		assertErr(t, json.UnmarshalPath(src, `$['a.b']["c\"d"]`, &v))
+		assertEq(t, "value", "escaped", v)
+	})
+	t.Run("no match", func(t *testing.T) {
+		v := "unchanged"
+		assertErr(t, json.UnmarshalPath(src, "$.items[5].id", &v))
+		assertEq(t, "value", "unchanged", v)
+	})
+	t.Run("reuse compiled path", func(t *testing.T) {
+		p, err := json.CreatePath("$.id")
+		assertErr(t, err)
+		assertEq(t, "string", "$.id", p.String())
+		for i, data := range []string{`{"id":1}`, `{"x":[1,2],"id":2}`} {
+			var id int
+			assertErr(t, p.Unmarshal([]byte(data), &id))
+			assertEq(t, "id", i+1, id)
+		}
+	})
+	t.Run("unexpected end in skipped value", func(t *testing.T) {
+		var id int
+		if err := json.UnmarshalPath([]byte(`{"x":[1,2`), "$.id", &id); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+	t.Run("type error", func(t *testing.T) {
+		check := func(t *testing.T, err error, line, column int, path string) {
+			t.Helper()
+			var terr *json.UnmarshalTypeError
+			if !errors.As(err, &terr) {
+				t.Fatalf("expected *json.UnmarshalTypeError but got %T: %v", err, err)
+			}
+			errLine, errColumn := json.ErrorPosition(src, err)
+			assertEq(t, "line", line, errLine)
+			assertEq(t, "column", column, errColumn)
+			assertEq(t, "path", path, terr.Path)
+		}
+		var id string
+		check(t, json.UnmarshalPath(src, "$.items[2].id", &id), 6, 27, "items[2].id")
+		var ids []string
+		check(t, json.UnmarshalPath(src, "$.items[*].id", &ids), 4, 12, "items[0].id")
+		var meta []struct {
+			X []int `json:"x"`
+		}
+		check(t, json.UnmarshalPath(src, "$.items[*].meta", &meta), 4, 56, "items[0].meta.x[2]")
+		var members []string
+		check(t, json.UnmarshalPath(src, "$.*", &members), 3, 12, "items")
+	})
+	t.Run("options", func(t *testing.T) {
+		data := []byte(`{"a":{"b":{"c":[1,2]}},"items":[{"id":1,"x":"a"},{"id":"2"},{"id":3}]}`)
+		var c []int
+		err := json.UnmarshalPath(data, "$.a.b.c", &c, json.DecodeWithLimits(json.DecodeLimits{MaxDepth: 2}))
+		var derr *json.DepthLimitError
+		if !errors.As(err, &derr) {
+			t.Fatalf("expected *json.DepthLimitError but got %T: %v", err, err)
+		}
+		var n interface{}
+		assertErr(t, json.UnmarshalPath(data, "$.items[0].id", &n, json.UseNumber()))
+		assertEq(t, "number", json.Number("1"), n)
+		var items []struct {
+			ID int `json:"id"`
+		}
+		err = json.UnmarshalPath(data, "$.items[*]", &items, json.CollectErrors())
+		var errs json.DecodeErrors
+		if !errors.As(err, &errs) {
+			t.Fatalf("expected json.DecodeErrors but got %T: %v", err, err)
+		}
+		assertEq(t, "collected errors", 1, len(errs))
+		assertEq(t, "path", "items[1].id", errs[0].(*json.UnmarshalTypeError).Path)
+		assertEq(t, "ids", "[{1} {0} {3}]", fmt.Sprint(items))
+		var item struct {
+			ID int `json:"id"`
+		}
+		if err := json.UnmarshalPath(data, "$.items[0]", &item, json.DisallowUnknownFields()); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+	t.Run("invalid path", func(t *testing.T) {
+		for _, path := range []string{"", "items", "$.", "$..id", "$[", "$[1", "$['a]", "$[a]", "$.a]"} {
+			var v interface{}
+			err := json.UnmarshalPath(src, path, &v)
+			var perr *json.InvalidPathError
+			if !errors.As(err, &perr) {
+				t.Fatalf("%q: expected *json.InvalidPathError but got %T", path, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,242 @@
+package json
+
+import (
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// Pointer is a parsed JSON Pointer ( RFC 6901 ).
+// A Pointer can be reused to look up many documents and is safe for concurrent use.
+type Pointer struct {
+	src    string
+	tokens []string
+}
+
+// ParsePointer parses a JSON Pointer such as "/a/b/0".
+// The empty string references the whole document.
+func ParsePointer(p string) (*Pointer, error) {
+	ptr := &Pointer{src: p}
+	if p == "" {
+		return ptr, nil
+	}
+	if p[0] != '/' {
+		return nil, errInvalidPointer("pointer must start with /", p, 0)
+	}
+	start := 1
+	for i := 1; i <= len(p); i++ {
+		if i < len(p) && p[i] != '/' {
+			continue
+		}
+		token := p[start:i]
+		if strings.IndexByte(token, '~') >= 0 {
+			unescaped, offset := unescapePointerToken(token)
+			if offset >= 0 {
+				return nil, errInvalidPointer("invalid escape sequence", p, start+offset)
+			}
+			token = unescaped
+		}
+		ptr.tokens = append(ptr.tokens, token)
+		start = i + 1
+	}
+	return ptr, nil
+}
+
+// unescapePointerToken replaces ~1 with / and ~0 with ~.
+// If token contains an invalid escape sequence, it returns the offset of it.
+func unescapePointerToken(token string) (string, int) {
+	b := make([]byte, 0, len(token))
+	for i := 0; i < len(token); i++ {
+		if token[i] != '~' {
+			b = append(b, token[i])
+			continue
+		}
+		if i+1 >= len(token) {
+			return "", i
+		}
+		switch token[i+1] {
+		case '0':
+			b = append(b, '~')
+		case '1':
+			b = append(b, '/')
+		default:
+			return "", i
+		}
+		i++
+	}
+	return string(b), -1
+}
+
+// String returns the source text of the pointer.
+func (p *Pointer) String() string {
+	return p.src
+}
+
+// Get returns a copy of the JSON-encoded value referenced by the pointer.
+// Values that are not on the way to the referenced value are skipped without being decoded.
+func (p *Pointer) Get(data []byte) (RawMessage, error) {
+	src := make([]byte, len(data)+1) // append nul byte to the end
+	copy(src, data)
+
+	cursor, err := p.find(src, nil)
+	if err != nil {
+		return nil, err
+	}
+	end, err := skipValue(src, cursor, int64(len(p.tokens)))
+	if err != nil {
+		return nil, err
+	}
+	raw := make(RawMessage, end-cursor)
+	copy(raw, src[cursor:end])
+	return raw, nil
+}
+
+// Unmarshal decodes the value referenced by the pointer and stores the result in the value pointed to by v.
+// Values that are not on the way to the referenced value are skipped without being decoded.
+// The options are applied in the same way as Unmarshal.
+func (p *Pointer) Unmarshal(data []byte, v interface{}, optFuncs ...DecodeOptionFunc) error {
+	src := make([]byte, len(data)+1) // append nul byte to the end
+	copy(src, data)
+
+	header := (*emptyInterface)(unsafe.Pointer(&v))
+
+	if err := validateType(header.typ, uintptr(header.ptr)); err != nil {
+		return err
+	}
+	opt := DecodeOption{}.apply(optFuncs)
+	if err := opt.limits.checkBytes(int64(len(data))); err != nil {
+		return err
+	}
+	dec, err := decodeCompileToGetDecoderWithConfig(header.typ, opt.config)
+	if err != nil {
+		return err
+	}
+	cursor, err := p.find(src, opt.limits)
+	if err != nil {
+		return err
+	}
+	rctx := takeRuntimeContext()
+	rctx.buf = src
+	rctx.option = opt
+	_, err = dec.decode(rctx, cursor, int64(len(p.tokens)), header.ptr)
+	err = collectedErrors(opt.Flag, rctx.errs, err)
+	err = finishErrorPath(err)
+	releaseRuntimeContext(rctx)
+	return err
+}
+
+// find returns the cursor of the value referenced by the pointer.
+// The depth of the containers on the way to the value is checked by limits.
+func (p *Pointer) find(buf []byte, limits *DecodeLimits) (int64, error) {
+	cursor := skipWhiteSpace(buf, 0)
+	for depth, token := range p.tokens {
+		if err := limits.checkDepth(int64(depth+1), buf[cursor], cursor); err != nil {
+			return 0, err
+		}
+		var (
+			c   int64
+			err error
+		)
+		switch buf[cursor] {
+		case '{':
+			c, err = findPointerMember(buf, cursor, int64(depth+1), token)
+		case '[':
+			c, err = findPointerElement(buf, cursor, int64(depth+1), token)
+		default:
+			c = -1
+		}
+		if err != nil {
+			return 0, err
+		}
+		if c < 0 {
+			return 0, errPointerNotFound(p.src, token)
+		}
+		cursor = skipWhiteSpace(buf, c)
+	}
+	return cursor, nil
+}
+
+// findPointerMember returns the cursor of the member value named key in the object at cursor,
+// or -1 if the object doesn't have the member.
+func findPointerMember(buf []byte, cursor, depth int64, key string) (int64, error) {
+	cursor = skipWhiteSpace(buf, cursor+1)
+	if buf[cursor] == '}' {
+		return -1, nil
+	}
+	for {
+		k, c, err := pathKeyDecoder.decodeByte(buf, cursor)
+		if err != nil {
+			return 0, err
+		}
+		cursor = skipWhiteSpace(buf, c)
+		if buf[cursor] != ':' {
+			return 0, errExpected("colon after object key", cursor)
+		}
+		cursor++
+		if string(k) == key {
+			return cursor, nil
+		}
+		c, err = skipValue(buf, cursor, depth)
+		if err != nil {
+			return 0, err
+		}
+		cursor = skipWhiteSpace(buf, c)
+		switch buf[cursor] {
+		case '}':
+			return -1, nil
+		case ',':
+			cursor++
+		default:
+			return 0, errExpected("comma after object element", cursor)
+		}
+	}
+}
+
+// findPointerElement returns the cursor of the element referenced by token in the array at cursor,
+// or -1 if token isn't an index of the array.
+func findPointerElement(buf []byte, cursor, depth int64, token string) (int64, error) {
+	index, ok := parsePointerIndex(token)
+	if !ok {
+		return -1, nil
+	}
+	cursor = skipWhiteSpace(buf, cursor+1)
+	if buf[cursor] == ']' {
+		return -1, nil
+	}
+	for i := 0; ; i++ {
+		if i == index {
+			return cursor, nil
+		}
+		c, err := skipValue(buf, cursor, depth)
+		if err != nil {
+			return 0, err
+		}
+		cursor = skipWhiteSpace(buf, c)
+		switch buf[cursor] {
+		case ']':
+			return -1, nil
+		case ',':
+			cursor = skipWhiteSpace(buf, cursor+1)
+		default:
+			return 0, errExpected("comma after array element", cursor)
+		}
+	}
+}
+
+// parsePointerIndex parses an array index token.
+// Leading zeros and "-" ( the element after the last one ) never reference an existing element.
+func parsePointerIndex(token string) (int, bool) {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, false
+	}
+	for i := 0; i < len(token); i++ {
+		if token[i] < '0' || '9' < token[i] {
+			return 0, false
+		}
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil {
+		return 0, false
+	}
+	return index, true
+}
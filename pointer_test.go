@@ -0,0 +1,97 @@
+package json_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+func TestGetPointer(t *testing.T) {
+	// example document from RFC 6901
+	src := []byte(`{
+  "foo": ["bar", "baz"],
+  "": 0,
+  "a/b": 1,
+  "c%d": 2,
+  "e^f": 3,
+  "g|h": 4,
+  "i\\j": 5,
+  "k\"l": 6,
+  " ": 7,
+  "m~n": 8
+}`)
+	tests := []struct {
+		pointer  string
+		expected string
+	}{
+		{"", string(src)},
+		{"/foo", `["bar", "baz"]`},
+		{"/foo/0", `"bar"`},
+		{"/foo/1", `"baz"`},
+		{"/", `0`},
+		{"/a~1b", `1`},
+		{"/c%d", `2`},
+		{"/e^f", `3`},
+		{"/g|h", `4`},
+		{"/i\\j", `5`},
+		{"/k\"l", `6`},
+		{"/ ", `7`},
+		{"/m~0n", `8`},
+	}
+	for _, test := range tests {
+		raw, err := json.GetPointer(src, test.pointer)
+		assertErr(t, err)
+		assertEq(t, test.pointer, test.expected, string(raw))
+	}
+	t.Run("not found", func(t *testing.T) {
+		for _, pointer := range []string{"/bar", "/foo/2", "/foo/-", "/foo/01", "/foo/0/x", "/a~1b/0"} {
+			_, err := json.GetPointer(src, pointer)
+			var perr *json.PointerNotFoundError
+			if !errors.As(err, &perr) {
+				t.Fatalf("%q: expected *json.PointerNotFoundError but got %v", pointer, err)
+			}
+		}
+	})
+	t.Run("invalid pointer", func(t *testing.T) {
+		for _, pointer := range []string{"foo", "/m~2n", "/m~"} {
+			_, err := json.GetPointer(src, pointer)
+			var perr *json.InvalidPointerError
+			if !errors.As(err, &perr) {
+				t.Fatalf("%q: expected *json.InvalidPointerError but got %v", pointer, err)
+			}
+		}
+	})
+}
+
+func TestUnmarshalPointer(t *testing.T) {
+	src := []byte(`{"users":[{"name":"alice","age":20},{"name":"bob","age":30}],"total":2}`)
+	var user struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+	assertErr(t, json.UnmarshalPointer(src, "/users/1", &user))
+	assertEq(t, "name", "bob", user.Name)
+	assertEq(t, "age", 30, user.Age)
+
+	p, err := json.ParsePointer("/total")
+	assertErr(t, err)
+	assertEq(t, "string", "/total", p.String())
+	var total int
+	assertErr(t, p.Unmarshal(src, &total))
+	assertEq(t, "total", 2, total)
+
+	var age interface{}
+	assertErr(t, json.UnmarshalPointer(src, "/users/0/age", &age, json.UseNumber()))
+	assertEq(t, "age", json.Number("20"), age)
+	err = json.UnmarshalPointer(src, "/users/0/age", &age, json.DecodeWithLimits(json.DecodeLimits{MaxDepth: 2}))
+	var derr *json.DepthLimitError
+	if !errors.As(err, &derr) {
+		t.Fatalf("expected *json.DepthLimitError but got %T: %v", err, err)
+	}
+	err = json.UnmarshalPointer(src, "/total", &total, json.DecodeWithLimits(json.DecodeLimits{MaxBytes: 10}))
+	var berr *json.BytesLimitError
+	if !errors.As(err, &berr) {
+		t.Fatalf("expected *json.BytesLimitError but got %T: %v", err, err)
+	}
+}
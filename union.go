@@ -0,0 +1,91 @@
+package json
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+
+	"github.com/goccy/go-json/internal/runtime"
+)
+
+// RegisterUnion registers the concrete types of an interface that are distinguished by the value of key.
+// iface is a nil pointer to the interface type, and types is a map from the values of key to
+// values of the interface whose dynamic types are the concrete types:
+//
+//	json.RegisterUnion((*Shape)(nil), "type", map[string]Shape{
+//		"circle": Circle{},
+//		"rect":   &Rect{},
+//	})
+//
+// Unmarshal reads the value of key of a JSON object to decode into the interface,
+// and decodes the object into a new value of the concrete type.
+// The object is also decoded by the concrete type, so the key is ignored unless the type has a field for it
+// ( with DisallowUnknownFields, the type needs a field for the key ).
+// An object without the key or with an unknown value of it is reported as UnmarshalTypeError.
+//
+// Marshal writes key with the value for the concrete type as the first member of the object
+// encoded from a struct in the interface ( e.g. a field or an element of the interface type ),
+// so that the object round-trips through Unmarshal. If the struct has a field for the key,
+// the field isn't encoded, so the value for the type is written instead of the value of the field.
+// To write the key for a single value, marshal a pointer to the interface.
+// The concrete types must be structs or pointers to structs that don't implement Marshaler,
+// MarshalerContext or encoding.TextMarshaler, because the key can't be written into their output.
+// The key isn't written either for a type that is encoded by a function of RegisterTypeEncoder.
+//
+// The compiled encoder and decoder of a type are cached, so RegisterUnion must be called
+// before the interface is encoded or decoded ( e.g. in init ).
+// RegisterUnion panics if the arguments are invalid.
+func RegisterUnion(iface interface{}, key string, types interface{}) {
+	ifaceType := reflect.TypeOf(iface)
+	if ifaceType == nil || ifaceType.Kind() != reflect.Ptr || ifaceType.Elem().Kind() != reflect.Interface {
+		panic(fmt.Sprintf("json: RegisterUnion of non interface pointer type %v", ifaceType))
+	}
+	ifaceType = ifaceType.Elem()
+	mapValue := reflect.ValueOf(types)
+	if mapValue.Kind() != reflect.Map || mapValue.Type().Key().Kind() != reflect.String || mapValue.Type().Elem() != ifaceType {
+		panic(fmt.Sprintf("json: RegisterUnion of %v with types of %T instead of map[string]%v", ifaceType, types, ifaceType))
+	}
+	u := &runtime.Union{
+		Key:   key,
+		Types: map[string]*runtime.Type{},
+		Names: map[*runtime.Type]string{},
+	}
+	iter := mapValue.MapRange()
+	for iter.Next() {
+		name := iter.Key().String()
+		if iter.Value().IsNil() {
+			panic(fmt.Sprintf("json: RegisterUnion of %v with nil type for %q", ifaceType, name))
+		}
+		if !isUnionStructType(iter.Value().Elem().Type()) {
+			panic(fmt.Sprintf("json: RegisterUnion of %v with %v for %q that is not a struct or a pointer to a struct without MarshalJSON and MarshalText", ifaceType, iter.Value().Elem().Type(), name))
+		}
+		typ := runtime.Type2RType(iter.Value().Elem().Type())
+		if other, exists := u.Names[typ]; exists {
+			panic(fmt.Sprintf("json: RegisterUnion of %v with %v for both %q and %q", ifaceType, typ, other, name))
+		}
+		u.Types[name] = typ
+		u.Names[typ] = name
+	}
+	runtime.RegisterUnion(runtime.Type2RType(ifaceType), u)
+}
+
+var (
+	unionMarshalerTypes = []reflect.Type{
+		reflect.TypeOf((*Marshaler)(nil)).Elem(),
+		reflect.TypeOf((*MarshalerContext)(nil)).Elem(),
+		reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem(),
+	}
+)
+
+// isUnionStructType reports whether typ is encoded as a struct, which the key of a union can be written into.
+func isUnionStructType(typ reflect.Type) bool {
+	for _, marshalerType := range unionMarshalerTypes {
+		if typ.Implements(marshalerType) {
+			return false
+		}
+	}
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ.Kind() == reflect.Struct
+}